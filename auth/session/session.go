@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/alexedwards/scs/pgxstore"
 	"github.com/alexedwards/scs/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const Lifetime = time.Hour * 24 * 7 // 7 days
@@ -20,3 +22,13 @@ func Init() {
 	Manager.Cookie.HttpOnly = true
 	Manager.Cookie.SameSite = http.SameSiteNoneMode
 }
+
+// InitStore stores the sessions in PostgreSQL using pool, expired sessions
+// are cleaned up every 12 hours. It calls Init if it was not called yet.
+func InitStore(pool *pgxpool.Pool) {
+	if Manager == nil {
+		Init()
+	}
+
+	Manager.Store = pgxstore.NewWithCleanupInterval(pool, 12*time.Hour)
+}
@@ -0,0 +1,146 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const defaultBatchSize = 1000
+
+// errStopIteration stops IterateBatches when the consumer of Iterate stops early.
+var errStopIteration = errors.New("dbx: stop iteration")
+
+type BatchOptions struct {
+	// KeyColumn is the unique column the batches are ordered and paged on, e.g. "id".
+	KeyColumn string
+
+	// Size is the number of rows per batch. Defaults to 1000.
+	Size int
+
+	// InTx runs each batch, the query and the callback, in its own transaction with WithTx.
+	// The executor passed to IterateBatches must be a TxBeginner, e.g. *pgxpool.Pool.
+	InTx bool
+}
+
+// IterateBatches walks the rows of the query built by b in batches ordered by opts.KeyColumn.
+// Each batch continues after the key of the last row of the previous batch ("key > $N"),
+// so that every batch is as fast as the first one unlike LIMIT/OFFSET.
+// The sorting and pagination of b are replaced and b itself is not changed.
+//
+// fn is called with every batch and the executor the batch was read with,
+// which is the transaction when opts.InTx is set.
+// key returns the value of opts.KeyColumn for a row.
+func IterateBatches[T any](ctx context.Context, db DBExecutor, b *SQLBuilder, scan pgx.RowToFunc[T], key func(T) any, opts BatchOptions, fn func(ctx context.Context, db DBExecutor, batch []T) error) error {
+	if opts.KeyColumn == "" {
+		return fmt.Errorf("%w: batch key", ErrEmptyColumn)
+	}
+
+	if opts.Size <= 0 {
+		opts.Size = defaultBatchSize
+	}
+
+	var beginner TxBeginner
+	if opts.InTx {
+		var ok bool
+		if beginner, ok = db.(TxBeginner); !ok {
+			return fmt.Errorf("dbx: batches in a transaction require a TxBeginner, got %T", db)
+		}
+	}
+
+	var last any
+	done := false
+
+	runBatch := func(db DBExecutor) error {
+		q := b.Clone()
+		if last != nil {
+			q.AddCompareFilter(opts.KeyColumn, OperatorGT, last)
+		}
+		q.order = []sortClause{}
+		q.tiebreaker = ""
+		q.AddSorting(opts.KeyColumn, "ASC")
+		q.offset = ""
+		q.AddPagination(opts.Size, 0)
+
+		sql, args, err := q.Build()
+		if err != nil {
+			return err
+		}
+
+		rows, err := db.Query(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+
+		batch, err := pgx.CollectRows(rows, scan)
+		if err != nil {
+			return err
+		}
+
+		if len(batch) < opts.Size {
+			done = true
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		last = key(batch[len(batch)-1])
+		if last == nil {
+			// Without a key the next batch would read the same rows again, forever.
+			return fmt.Errorf("dbx: batch key %s of the last row is nil", opts.KeyColumn)
+		}
+		return fn(ctx, db, batch)
+	}
+
+	for !done {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var err error
+		if beginner != nil {
+			err = WithTx(ctx, beginner, func(tx pgx.Tx) error {
+				return runBatch(tx)
+			})
+		} else {
+			err = runBatch(db)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Iterate is like IterateBatches but yields one row at a time:
+//
+//	for user, err := range dbx.Iterate(ctx, pool, b, pgx.RowToStructByName[User], userID, opts) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// The iteration ends after yielding an error.
+func Iterate[T any](ctx context.Context, db DBExecutor, b *SQLBuilder, scan pgx.RowToFunc[T], key func(T) any, opts BatchOptions) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		err := IterateBatches(ctx, db, b, scan, key, opts, func(ctx context.Context, db DBExecutor, batch []T) error {
+			for _, row := range batch {
+				if !yield(row, nil) {
+					return errStopIteration
+				}
+			}
+			return nil
+		})
+
+		if err != nil && !errors.Is(err, errStopIteration) {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
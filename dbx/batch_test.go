@@ -0,0 +1,55 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var errQueryRecorded = errors.New("query recorded")
+
+// recordingExecutor records the first query and stops the iteration.
+type recordingExecutor struct {
+	sql  string
+	args []any
+}
+
+func (e *recordingExecutor) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errQueryRecorded
+}
+
+func (e *recordingExecutor) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	e.sql, e.args = sql, args
+	return nil, errQueryRecorded
+}
+
+func (e *recordingExecutor) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return nil
+}
+
+func TestIterateBatchesDropsOrderByArgs(t *testing.T) {
+	b := NewSQLBuilder("SELECT * FROM users")
+	b.AddRankSorting("name", "foo", "english", "DESC")
+	b.AddCompareFilter("x", OperatorEQ, 1)
+
+	db := &recordingExecutor{}
+	err := IterateBatches(context.Background(), db, b, pgx.RowToMap, func(row map[string]any) any { return row["id"] },
+		BatchOptions{KeyColumn: "id"},
+		func(ctx context.Context, db DBExecutor, batch []map[string]any) error { return nil },
+	)
+	if !errors.Is(err, errQueryRecorded) {
+		t.Fatalf("IterateBatches() error = %v, want %v", err, errQueryRecorded)
+	}
+
+	wantSQL := "SELECT * FROM users WHERE x = $1 ORDER BY id ASC LIMIT 1000"
+	if db.sql != wantSQL {
+		t.Errorf("sql = %q, want %q", db.sql, wantSQL)
+	}
+	if want := []any{1}; !slices.Equal(db.args, want) {
+		t.Errorf("args = %v, want %v", db.args, want)
+	}
+}
@@ -0,0 +1,143 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CopyExecutor can bulk load rows with COPY, e.g. *pgxpool.Pool, *pgx.Conn or pgx.Tx.
+type CopyExecutor interface {
+	DBExecutor
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+type BulkOptions struct {
+	// Omit are the columns that are not loaded, e.g. "id" generated by the database.
+	Omit []string
+
+	// Conflict are the columns of the unique constraint used for ON CONFLICT, e.g. "email".
+	// When set or when DoNothing is set, the rows are copied into a staging table first and
+	// then upserted into the table with "INSERT ... SELECT ... ON CONFLICT".
+	Conflict []string
+
+	// Update are the columns updated on conflict. Defaults to every loaded column except Conflict.
+	Update []string
+
+	// DoNothing skips the conflicting rows instead of updating them.
+	DoNothing bool
+}
+
+type BulkResult struct {
+	Inserted int64
+	Updated  int64
+}
+
+// BulkInsert loads rows into table with COPY, which is much faster than INSERTs for
+// thousands of rows. The columns come from the `db` tags of T, see InsertBuilder.AddStruct.
+//
+// Without opts.Conflict and opts.DoNothing the rows are copied into table directly and
+// any conflict fails the whole load. Otherwise the rows are copied into a temporary
+// staging table and upserted from there within a transaction (a SAVEPOINT when db or
+// ctx already carries one), so that db must also be a TxBeginner, as the pool and pgx.Tx are.
+//
+// The rows must not repeat the Conflict values, as PostgreSQL can not update a row twice in one statement.
+//
+// A transaction carried by ctx, see InTx, is used instead of db.
+func BulkInsert[T any](ctx context.Context, db CopyExecutor, table string, rows []T, opts BulkOptions) (BulkResult, error) {
+	if tx, ok := TxFromCtx(ctx); ok {
+		db = tx
+	}
+
+	if len(rows) == 0 {
+		return BulkResult{}, nil
+	}
+
+	columns, _, err := structColumns(rows[0], opts.Omit...)
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	source := pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+		_, values, err := structColumns(rows[i], opts.Omit...)
+		return values, err
+	})
+
+	if len(opts.Conflict) == 0 && !opts.DoNothing {
+		inserted, err := db.CopyFrom(ctx, tableIdentifier(table), columns, source)
+		if err != nil {
+			return BulkResult{}, fmt.Errorf("copy into %s: %w", table, err)
+		}
+		return BulkResult{Inserted: inserted}, nil
+	}
+
+	beginner, ok := db.(TxBeginner)
+	if !ok {
+		return BulkResult{}, fmt.Errorf("dbx: bulk upsert requires a TxBeginner, got %T", db)
+	}
+
+	var result BulkResult
+	err = withTx(ctx, beginner, TxOptions{}, func(ctx context.Context, tx pgx.Tx) error {
+		result, err = bulkUpsert(ctx, tx, table, columns, source, opts)
+		return err
+	})
+
+	return result, err
+}
+
+// bulkUpsert copies source into a staging table and upserts it into table.
+func bulkUpsert(ctx context.Context, tx pgx.Tx, table string, columns []string, source pgx.CopyFromSource, opts BulkOptions) (BulkResult, error) {
+	target := tableIdentifier(table)
+	staging := pgx.Identifier{"dbx_staging_" + strings.Join(target, "_")}
+	columnList := strings.Join(columns, ", ")
+
+	// Only the column types are copied, so that omitted NOT NULL columns
+	// (e.g. identity keys) do not fail the COPY into the staging table.
+	createSQL := fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+		staging.Sanitize(), columnList, target.Sanitize())
+	if _, err := tx.Exec(ctx, createSQL); err != nil {
+		return BulkResult{}, fmt.Errorf("create staging table for %s: %w", table, err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, staging, columns, source); err != nil {
+		return BulkResult{}, fmt.Errorf("copy into staging table for %s: %w", table, err)
+	}
+
+	// xmax is 0 for the rows inserted by the statement and set for the rows it updated.
+	upsertSQL := fmt.Sprintf(
+		"WITH upserted AS (INSERT INTO %s (%s) SELECT %s FROM %s%s RETURNING (xmax = 0) AS inserted) "+
+			"SELECT count(*) FILTER (WHERE inserted), count(*) FILTER (WHERE NOT inserted) FROM upserted",
+		target.Sanitize(), columnList, columnList, staging.Sanitize(),
+		conflictClause(columns, opts.Conflict, opts.Update, opts.DoNothing, !opts.DoNothing),
+	)
+
+	var result BulkResult
+	if err := tx.QueryRow(ctx, upsertSQL).Scan(&result.Inserted, &result.Updated); err != nil {
+		return BulkResult{}, fmt.Errorf("upsert into %s: %w", table, err)
+	}
+
+	// Dropped right away so that the same table can be loaded again in the same transaction.
+	if _, err := tx.Exec(ctx, "DROP TABLE "+staging.Sanitize()); err != nil {
+		return BulkResult{}, fmt.Errorf("drop staging table for %s: %w", table, err)
+	}
+
+	return result, nil
+}
+
+// tableIdentifier splits a possibly schema qualified table, e.g. `public.users` or `public."Users"`,
+// into the names PostgreSQL resolves it to: quoted parts as is and unquoted parts in lowercase.
+// Every statement of BulkInsert uses the identifier so that they all refer to the same table.
+func tableIdentifier(table string) pgx.Identifier {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && part[0] == '"' && part[len(part)-1] == '"' {
+			parts[i] = strings.ReplaceAll(part[1:len(part)-1], `""`, `"`)
+			continue
+		}
+		parts[i] = strings.ToLower(part)
+	}
+	return pgx.Identifier(parts)
+}
@@ -0,0 +1,212 @@
+package dbx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Condition is a composable WHERE clause node.
+// It is rendered against a SQLBuilder so that its args share the builder's "$N" numbering.
+type Condition interface {
+	// build appends the condition's args to b and returns its SQL.
+	// An empty string means the condition was skipped (e.g. nil value).
+	build(b *SQLBuilder) string
+}
+
+type conditionFunc func(b *SQLBuilder) string
+
+func (f conditionFunc) build(b *SQLBuilder) string {
+	return f(b)
+}
+
+// And groups conditions with " AND ", e.g. "(a AND b)".
+func And(conditions ...Condition) Condition {
+	return group(" AND ", conditions)
+}
+
+// Or groups conditions with " OR ", e.g. "(a OR b)".
+func Or(conditions ...Condition) Condition {
+	return group(" OR ", conditions)
+}
+
+// Not negates a condition, e.g. "NOT (a)".
+func Not(condition Condition) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		if condition == nil {
+			return ""
+		}
+
+		sql := condition.build(b)
+		if sql == "" {
+			return ""
+		}
+
+		return fmt.Sprintf("NOT (%s)", sql)
+	})
+}
+
+// group renders non-empty conditions joined by sep and wrapped in parentheses.
+// A group with a single condition is rendered without extra parentheses.
+func group(sep string, conditions []Condition) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		parts := []string{}
+		for _, condition := range conditions {
+			if condition == nil {
+				continue
+			}
+			if sql := condition.build(b); sql != "" {
+				parts = append(parts, sql)
+			}
+		}
+
+		switch len(parts) {
+		case 0:
+			return ""
+		case 1:
+			return parts[0]
+		default:
+			return "(" + strings.Join(parts, sep) + ")"
+		}
+	})
+}
+
+// Raw is a custom condition with placeholders, see SQLBuilder.AppendWhere.
+func Raw(condition string, args ...any) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		if condition == "" {
+			return ""
+		}
+
+		sql, args, ok := b.bind(condition, args)
+		if !ok {
+			return ""
+		}
+
+		b.args = append(b.args, args...)
+		b.argNum += len(args)
+
+		// Parenthesized so that an "OR" inside can not leak into the enclosing group.
+		return "(" + sql + ")"
+	})
+}
+
+// Compare is a condition like "column = $N", "column >= $N".
+// OperatorIN and OperatorNotIN take a slice and render "column = ANY($N)" and "column <> ALL($N)",
+// OperatorIsNull and OperatorIsNotNull ignore the value.
+func Compare(column string, operator Operator, value any) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		noValue := operator == OperatorIsNull || operator == OperatorIsNotNull
+		if value == nil && !noValue {
+			return ""
+		}
+
+		if column == "" {
+			b.addErr(ErrEmptyColumn, fmt.Sprintf("%s filter", operator))
+			return ""
+		}
+
+		operatorSQL := parseOperator(operator)
+		if operatorSQL == "" {
+			b.addErr(ErrInvalidOperator, fmt.Sprintf("%q on %s", operator, column))
+			return ""
+		}
+
+		if noValue {
+			return fmt.Sprintf("%s %s", column, operatorSQL)
+		}
+
+		b.args = append(b.args, value)
+
+		switch operator {
+		case OperatorIN:
+			return fmt.Sprintf("%s = ANY($%d)", column, b.nextArg())
+		case OperatorNotIN:
+			return fmt.Sprintf("%s <> ALL($%d)", column, b.nextArg())
+		default:
+			return fmt.Sprintf("%s %s $%d", column, operatorSQL, b.nextArg())
+		}
+	})
+}
+
+// Between is a condition like "column BETWEEN $N AND $N+1"
+func Between(column string, from, to any) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		if from == nil || to == nil {
+			return ""
+		}
+
+		if column == "" {
+			b.addErr(ErrEmptyColumn, "BETWEEN filter")
+			return ""
+		}
+
+		b.args = append(b.args, from, to)
+		return fmt.Sprintf("%s BETWEEN $%d AND $%d", column, b.nextArg(), b.nextArg())
+	})
+}
+
+// Array is a condition like "column = ANY($N)" for array values
+func Array(column string, values []any) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		if len(values) == 0 {
+			return ""
+		}
+
+		if column == "" {
+			b.addErr(ErrEmptyColumn, "ANY filter")
+			return ""
+		}
+
+		b.args = append(b.args, values)
+		return fmt.Sprintf("%s = ANY($%d)", column, b.nextArg())
+	})
+}
+
+// StartsWith is a LIKE condition for prefix matching ("value%").
+// LIKE wildcards in value are escaped so that they match literally.
+func StartsWith(column string, value string, caseSensitive bool) Condition {
+	if value == "" {
+		return like(column, "", caseSensitive)
+	}
+	return like(column, likeEscaper.Replace(value)+"%", caseSensitive)
+}
+
+// EndsWith is a LIKE condition for suffix matching ("%value").
+// LIKE wildcards in value are escaped so that they match literally.
+func EndsWith(column string, value string, caseSensitive bool) Condition {
+	if value == "" {
+		return like(column, "", caseSensitive)
+	}
+	return like(column, "%"+likeEscaper.Replace(value), caseSensitive)
+}
+
+// Contains is a LIKE condition for substring matching ("%value%").
+// LIKE wildcards in value are escaped so that they match literally.
+func Contains(column string, value string, caseSensitive bool) Condition {
+	if value == "" {
+		return like(column, "", caseSensitive)
+	}
+	return like(column, "%"+likeEscaper.Replace(value)+"%", caseSensitive)
+}
+
+// like is a LIKE condition with custom pattern (private helper)
+func like(column string, pattern string, caseSensitive bool) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		if pattern == "" {
+			return ""
+		}
+
+		if column == "" {
+			b.addErr(ErrEmptyColumn, "LIKE filter")
+			return ""
+		}
+
+		operator := "LIKE"
+		if !caseSensitive {
+			operator = "ILIKE"
+		}
+
+		b.args = append(b.args, pattern)
+		return fmt.Sprintf("%s %s $%d", column, operator, b.nextArg())
+	})
+}
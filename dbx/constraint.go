@@ -0,0 +1,116 @@
+package dbx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mudgallabs/tantra/apires"
+	"github.com/mudgallabs/tantra/service"
+)
+
+// constraintDetail matches the key of PostgreSQL constraint details,
+// e.g. "Key (email)=(a@b.c) already exists.".
+var constraintDetail = regexp.MustCompile(`^Key \((.+?)\)=\((.*)\)`)
+
+// Messages used for constraint violations without a registered message.
+var constraintMessages = map[ErrorKind]string{
+	ErrorKindUniqueViolation:     "Already exists",
+	ErrorKindExclusionViolation:  "Conflicts with an existing value",
+	ErrorKindForeignKeyViolation: "Does not exist",
+	ErrorKindNotNullViolation:    "Is required",
+	ErrorKindCheckViolation:      "Is invalid",
+}
+
+// ConstraintField is the API property a database constraint guards.
+type ConstraintField struct {
+	// PropertyPath is the request property, e.g. "email".
+	PropertyPath string
+
+	// Message can be shown to the API users on the UI, e.g. "Email is already taken".
+	Message string
+}
+
+// ConstraintRegistry maps PostgreSQL constraint names to API properties so that
+// constraint violations can be returned as input validation errors.
+type ConstraintRegistry struct {
+	fields map[string]ConstraintField
+}
+
+func NewConstraintRegistry() *ConstraintRegistry {
+	return &ConstraintRegistry{
+		fields: map[string]ConstraintField{},
+	}
+}
+
+// Register maps the constraint, e.g. "users_email_key", to the property and message.
+func (r *ConstraintRegistry) Register(constraint, propertyPath, message string) {
+	r.fields[constraint] = ConstraintField{
+		PropertyPath: propertyPath,
+		Message:      message,
+	}
+}
+
+// InputErrors converts a constraint violation error into input validation errors.
+// Constraints that are not registered fall back to the column reported by PostgreSQL
+// and a generic message. It returns false if err is not a constraint violation.
+func (r *ConstraintRegistry) InputErrors(err error) (service.InputValidationErrors, bool) {
+	classified := ClassifyError(err)
+	if classified == nil || !classified.IsConstraintViolation() {
+		return nil, false
+	}
+
+	columns, value := parseConstraintDetail(classified.Detail)
+
+	field, ok := r.fields[classified.Constraint]
+	if !ok {
+		field = ConstraintField{
+			PropertyPath: classified.Column,
+			Message:      constraintMessages[classified.Kind],
+		}
+		if field.PropertyPath == "" && len(columns) == 1 {
+			field.PropertyPath = columns[0]
+		}
+	}
+
+	description := string(classified.Kind)
+	if classified.Constraint != "" {
+		description = fmt.Sprintf("%s on %s", classified.Kind, classified.Constraint)
+	}
+
+	var invalidValue any
+	if value != "" {
+		invalidValue = value
+	}
+
+	return service.NewInputValidationErrorsWithError(
+		apires.NewApiError(field.Message, description, field.PropertyPath, invalidValue),
+	), true
+}
+
+// ServiceError returns the kind of service error and the error to report for err,
+// so that it can be passed to httpx.ServiceErrResponse as is.
+// Constraint violations become service.ErrInvalidInput with input validation errors.
+func (r *ConstraintRegistry) ServiceError(err error) (service.Error, error) {
+	if errs, ok := r.InputErrors(err); ok {
+		return service.ErrInvalidInput, errs
+	}
+
+	classified := ClassifyError(err)
+	if classified == nil {
+		return service.ErrNone, nil
+	}
+
+	return classified.ServiceError(), err
+}
+
+// parseConstraintDetail returns the key columns and value of a constraint detail.
+func parseConstraintDetail(detail string) ([]string, string) {
+	match := constraintDetail.FindStringSubmatch(detail)
+	if match == nil {
+		return nil, ""
+	}
+
+	columns := strings.Split(match[1], ", ")
+	return columns, match[2]
+}
@@ -2,15 +2,14 @@ package dbx
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"time"
 
-	"github.com/alexedwards/scs/pgxstore"
-	pgxdecimal "github.com/jackc/pgx-shopspring-decimal"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/mudgallabs/tantra/auth/session"
 	"github.com/mudgallabs/tantra/logger"
 )
 
@@ -20,39 +19,42 @@ type DBExecutor interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
+// Init connects to the database at url with the default options.
+// The session store is not set up here anymore, see session.InitStore.
 func Init(url string) (*pgxpool.Pool, error) {
-	l := logger.Get()
+	return New(context.Background(), url)
+}
+
+// New connects to the database at url, configured by opts, and checks the connection.
+// `decimal.Decimal` is always registered so that it can be used while scanning or inserting records.
+func New(ctx context.Context, url string, opts ...Option) (*pgxpool.Pool, error) {
+	l := logger.FromCtx(ctx)
+
+	o := &options{
+		tracer: NewTracer(TracerConfig{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
 
 	l.Info("connecting to database")
 
 	config, err := pgxpool.ParseConfig(url)
 	if err != nil {
-		log.Panic(err)
-		return nil, err
+		return nil, fmt.Errorf("parse database url: %w", err)
 	}
 
-	// So that we can log SQL query on execution.
-	config.ConnConfig.Tracer = &myQueryTracer{}
+	o.apply(config)
 
-	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
-		// Register `pgxdecimal` so that we can use `decimal.Decimal` for values while scaning or inserting records.
-		pgxdecimal.Register(conn.TypeMap())
-		return nil
-	}
-
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
-		log.Panic(err)
-		return nil, err
+		return nil, fmt.Errorf("create database pool: %w", err)
 	}
 
-	session.Manager.Store = pgxstore.NewWithCleanupInterval(pool, 12*time.Hour)
-
 	// Checking if the connection to the DB is working fine.
-	err = pool.Ping(context.Background())
-	if err != nil {
-		log.Panic(err)
-		return nil, err
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
 	}
 
 	l.Info("connected to database")
@@ -60,48 +62,153 @@ func Init(url string) (*pgxpool.Pool, error) {
 	return pool, nil
 }
 
-type myQueryTracer struct {
+// TxBeginner starts transactions, e.g. *pgxpool.Pool or *pgx.Conn.
+// A pgx.Tx is a TxBeginner too, its transactions are SAVEPOINTs within it.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
-func (tracer *myQueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
-	l := logger.FromCtx(ctx)
-	l.Debugw("executing SQL query", "sqlstr", data.SQL, "args", data.Args)
-	return ctx
-}
+type TxOptions struct {
+	pgx.TxOptions
 
-func (tracer *myQueryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
-	if data.Err != nil {
-		l := logger.FromCtx(ctx)
-		l.Debugw("error executing SQL query", "err", data.Err)
-	}
+	// MaxRetries is how many times fn is retried after a serialization failure (40001)
+	// or a deadlock (40P01). fn must be safe to run again. Savepoints are never retried,
+	// the error is returned so that the outermost transaction is retried instead.
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry, doubled for every later retry.
+	// Defaults to 50ms.
+	RetryBackoff time.Duration
 }
 
+const defaultRetryBackoff = 50 * time.Millisecond
+
 // WithTx runs fn within a transaction.
 // It commits if fn returns nil, or rolls back if fn returns an error or panics.
-func WithTx(ctx context.Context, db *pgxpool.Pool, fn func(tx pgx.Tx) error) (err error) {
+// If db is a pgx.Tx, or ctx already carries a transaction (see InTx),
+// fn runs within a SAVEPOINT that is released or rolled back instead.
+// Hooks to run after the commit are registered with AfterCommitTx.
+func WithTx(ctx context.Context, db TxBeginner, fn func(tx pgx.Tx) error) error {
+	return WithTxOptions(ctx, db, TxOptions{}, fn)
+}
+
+// WithTxOptions is like WithTx but begins the transaction with opts
+// (isolation level, access mode, deferrable) and retries it on serialization failures and deadlocks.
+// The pgx.TxOptions are ignored for SAVEPOINTs.
+func WithTxOptions(ctx context.Context, db TxBeginner, opts TxOptions, fn func(tx pgx.Tx) error) error {
+	return withTx(ctx, db, opts, func(ctx context.Context, tx pgx.Tx) error {
+		return fn(tx)
+	})
+}
+
+// withTx runs fn within a transaction (or a SAVEPOINT when nested) stored in the context passed to fn.
+func withTx(ctx context.Context, db TxBeginner, opts TxOptions, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	l := logger.FromCtx(ctx)
+
+	if tx, ok := TxFromCtx(ctx); ok {
+		db = tx
+	}
+	_, nested := db.(pgx.Tx)
+
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, opts.TxOptions, nested, fn)
+		if err == nil || nested || attempt >= opts.MaxRetries || !isRetryable(err) {
+			return err
+		}
+
+		wait := backoff << attempt
+		wait += rand.N(wait/2 + 1) // jitter so that conflicting transactions do not retry in lockstep
+		l.Debugf("[tx] retrying in %s after attempt %d: %v", wait, attempt+1, err)
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+func runTx(ctx context.Context, db TxBeginner, opts pgx.TxOptions, nested bool, fn func(ctx context.Context, tx pgx.Tx) error) (err error) {
 	l := logger.FromCtx(ctx)
 	start := time.Now()
 
-	tx, err := db.Begin(ctx)
+	label := "[tx]"
+	if nested {
+		label = "[savepoint]"
+	}
+
+	tx, err := beginTx(ctx, db, opts, nested)
 	if err != nil {
 		return err
 	}
 
+	state := &txState{tx: tx}
+	if parent, ok := txStateFromCtx(ctx); ok && parent.tx == db {
+		state.parent = parent
+	} else if dbTx, ok := db.(pgx.Tx); ok {
+		// WithTx(ctx, tx, ...) within WithTx, whose ctx does not carry the enclosing transaction.
+		if parent, ok := txStates.Load(dbTx); ok {
+			state.parent = parent.(*txState)
+		} else {
+			state.detached = true
+		}
+	}
+	if state.parent != nil {
+		state.detached = state.parent.detached
+	}
+
+	txStates.Store(tx, state)
+
 	defer func() {
+		txStates.Delete(tx)
+
 		duration := time.Since(start)
 		if p := recover(); p != nil {
 			_ = tx.Rollback(ctx)
-			l.Debugf("[tx] panic after %s: %v", duration, p)
+			l.Debugf("%s panic after %s: %v", label, duration, p)
 			panic(p)
 		} else if err != nil {
 			_ = tx.Rollback(ctx)
-			l.Debugf("[tx] rolled back after %s: %v", duration, err)
+			l.Debugf("%s rolled back after %s: %v", label, duration, err)
 		} else {
 			err = tx.Commit(ctx)
-			l.Debugf("[tx] committed after %s", duration)
+			if err != nil {
+				l.Debugf("%s commit failed after %s: %v", label, duration, err)
+				return
+			}
+			l.Debugf("%s committed after %s", label, duration)
+
+			if state.parent != nil {
+				// Released SAVEPOINT, the hooks wait for the enclosing transaction.
+				state.parent.hooks = append(state.parent.hooks, state.hooks...)
+			} else {
+				runHooks(ctx, state.hooks)
+			}
 		}
 	}()
 
-	err = fn(tx)
+	err = fn(contextWithTx(ctx, state), tx)
 	return
 }
+
+func beginTx(ctx context.Context, db TxBeginner, opts pgx.TxOptions, nested bool) (pgx.Tx, error) {
+	type txOptionsBeginner interface {
+		BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	}
+
+	if b, ok := db.(txOptionsBeginner); ok && !nested {
+		return b.BeginTx(ctx, opts)
+	}
+
+	return db.Begin(ctx)
+}
+
+// isRetryable reports whether a transaction failed with an error it may succeed after retrying.
+func isRetryable(err error) bool {
+	return IsSerializationFailure(err) || IsDeadlock(err)
+}
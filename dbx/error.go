@@ -2,16 +2,251 @@ package dbx
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mudgallabs/tantra/repository"
+	"github.com/mudgallabs/tantra/service"
 )
 
-const pgErrUniqueViolation = "23505" // PostgreSQL error code for unique constraint violation
+// Errors that SQLBuilder may return from Build.
+var (
+	ErrInvalidOperator = errors.New("dbx: invalid operator")
+	ErrEmptyColumn     = errors.New("dbx: empty column")
+	ErrArgCount        = errors.New("dbx: mismatched argument count")
+	ErrMissingWhere    = errors.New("dbx: UPDATE or DELETE without WHERE conditions")
+)
+
+const (
+	pgErrNotNullViolation     = "23502" // PostgreSQL error code for not null constraint violation
+	pgErrForeignKeyViolation  = "23503" // PostgreSQL error code for foreign key constraint violation
+	pgErrUniqueViolation      = "23505" // PostgreSQL error code for unique constraint violation
+	pgErrCheckViolation       = "23514" // PostgreSQL error code for check constraint violation
+	pgErrExclusionViolation   = "23P01" // PostgreSQL error code for exclusion constraint violation
+	pgErrSerializationFailure = "40001" // PostgreSQL error code for serialization failure
+	pgErrDeadlockDetected     = "40P01" // PostgreSQL error code for deadlock detected
+	pgErrLockNotAvailable     = "55P03" // PostgreSQL error code for lock timeout
+	pgErrQueryCanceled        = "57014" // PostgreSQL error code for statement timeout or cancel
+	pgErrAdminShutdown        = "57P01" // PostgreSQL error code for server shutdown
+	pgErrCrashShutdown        = "57P02" // PostgreSQL error code for server crash
+	pgErrCannotConnectNow     = "57P03" // PostgreSQL error code for server starting up
+	pgErrClassConnection      = "08"    // PostgreSQL error class for connection exceptions
+)
+
+// ErrorKind is the kind of a database error.
+type ErrorKind string
+
+const (
+	ErrorKindUnknown              ErrorKind = "unknown"
+	ErrorKindNoRows               ErrorKind = "no rows"
+	ErrorKindUniqueViolation      ErrorKind = "unique violation"
+	ErrorKindForeignKeyViolation  ErrorKind = "foreign key violation"
+	ErrorKindNotNullViolation     ErrorKind = "not null violation"
+	ErrorKindCheckViolation       ErrorKind = "check violation"
+	ErrorKindExclusionViolation   ErrorKind = "exclusion violation"
+	ErrorKindSerializationFailure ErrorKind = "serialization failure"
+	ErrorKindDeadlock             ErrorKind = "deadlock"
+	ErrorKindLockTimeout          ErrorKind = "lock timeout"
+	ErrorKindStatementTimeout     ErrorKind = "statement timeout"
+	ErrorKindConnection           ErrorKind = "connection"
+)
+
+// DBError is a classified database error.
+type DBError struct {
+	Kind ErrorKind
+
+	// Code is the PostgreSQL SQLSTATE, empty if the error did not come from the server.
+	Code string
+
+	// Constraint, Table and Column name the database objects involved, when PostgreSQL reports them.
+	Constraint string
+	Table      string
+	Column     string
+
+	// Detail is the PostgreSQL detail message, e.g. "Key (email)=(a@b.c) already exists."
+	Detail string
+
+	Err error
+}
+
+func (e *DBError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// IsConstraintViolation reports whether the error is caused by a constraint (integrity) violation.
+func (e *DBError) IsConstraintViolation() bool {
+	switch e.Kind {
+	case ErrorKindUniqueViolation, ErrorKindForeignKeyViolation, ErrorKindNotNullViolation,
+		ErrorKindCheckViolation, ErrorKindExclusionViolation:
+		return true
+	default:
+		return false
+	}
+}
+
+// RepositoryError returns the repository error for the kind, or nil if there is none:
+// repository.ErrNotFound for no rows and repository.ErrConflict for
+// unique, exclusion and foreign key violations.
+func (e *DBError) RepositoryError() error {
+	switch e.Kind {
+	case ErrorKindNoRows:
+		return repository.ErrNotFound
+	case ErrorKindUniqueViolation, ErrorKindExclusionViolation, ErrorKindForeignKeyViolation:
+		return repository.ErrConflict
+	default:
+		return nil
+	}
+}
+
+// ServiceError returns the kind of service error to report for the database error.
+func (e *DBError) ServiceError() service.Error {
+	switch e.Kind {
+	case ErrorKindNoRows:
+		return service.ErrNotFound
+	case ErrorKindUniqueViolation, ErrorKindExclusionViolation:
+		return service.ErrConflict
+	case ErrorKindForeignKeyViolation, ErrorKindNotNullViolation, ErrorKindCheckViolation:
+		return service.ErrInvalidInput
+	default:
+		return service.ErrInternalServerError
+	}
+}
+
+// ClassifyError classifies err, returning nil if err is nil.
+func ClassifyError(err error) *DBError {
+	if err == nil {
+		return nil
+	}
+
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return dbErr
+	}
+
+	classified := &DBError{Kind: ErrorKindUnknown, Err: err}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		classified.Kind = ErrorKindNoRows
+		return classified
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		classified.Kind = pgErrorKind(pgErr.Code)
+		classified.Code = pgErr.Code
+		classified.Constraint = pgErr.ConstraintName
+		classified.Table = pgErr.TableName
+		classified.Column = pgErr.ColumnName
+		classified.Detail = pgErr.Detail
+		return classified
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		classified.Kind = ErrorKindConnection
+	}
+
+	return classified
+}
+
+func pgErrorKind(code string) ErrorKind {
+	switch code {
+	case pgErrUniqueViolation:
+		return ErrorKindUniqueViolation
+	case pgErrForeignKeyViolation:
+		return ErrorKindForeignKeyViolation
+	case pgErrNotNullViolation:
+		return ErrorKindNotNullViolation
+	case pgErrCheckViolation:
+		return ErrorKindCheckViolation
+	case pgErrExclusionViolation:
+		return ErrorKindExclusionViolation
+	case pgErrSerializationFailure:
+		return ErrorKindSerializationFailure
+	case pgErrDeadlockDetected:
+		return ErrorKindDeadlock
+	case pgErrLockNotAvailable:
+		return ErrorKindLockTimeout
+	case pgErrQueryCanceled:
+		return ErrorKindStatementTimeout
+	case pgErrAdminShutdown, pgErrCrashShutdown, pgErrCannotConnectNow:
+		return ErrorKindConnection
+	}
+
+	if strings.HasPrefix(code, pgErrClassConnection) {
+		return ErrorKindConnection
+	}
+
+	return ErrorKindUnknown
+}
+
+// TranslateError wraps err with the matching repository error so that callers
+// can check it with errors.Is(err, repository.ErrConflict) while keeping the original error.
+// Errors without a matching repository error are returned as is.
+func TranslateError(err error) error {
+	classified := ClassifyError(err)
+	if classified == nil {
+		return nil
+	}
+
+	if repoErr := classified.RepositoryError(); repoErr != nil {
+		return fmt.Errorf("%w: %w", repoErr, classified)
+	}
+
+	return err
+}
 
 func IsUniqueViolation(err error) bool {
+	return hasPgErrCode(err, pgErrUniqueViolation)
+}
+
+func IsForeignKeyViolation(err error) bool {
+	return hasPgErrCode(err, pgErrForeignKeyViolation)
+}
+
+func IsNotNullViolation(err error) bool {
+	return hasPgErrCode(err, pgErrNotNullViolation)
+}
+
+func IsCheckViolation(err error) bool {
+	return hasPgErrCode(err, pgErrCheckViolation)
+}
+
+func IsExclusionViolation(err error) bool {
+	return hasPgErrCode(err, pgErrExclusionViolation)
+}
+
+func IsSerializationFailure(err error) bool {
+	return hasPgErrCode(err, pgErrSerializationFailure)
+}
+
+func IsDeadlock(err error) bool {
+	return hasPgErrCode(err, pgErrDeadlockDetected)
+}
+
+func IsLockTimeout(err error) bool {
+	return hasPgErrCode(err, pgErrLockNotAvailable)
+}
+
+func IsStatementTimeout(err error) bool {
+	return hasPgErrCode(err, pgErrQueryCanceled)
+}
+
+func IsConnectionError(err error) bool {
+	classified := ClassifyError(err)
+	return classified != nil && classified.Kind == ErrorKindConnection
+}
+
+func hasPgErrCode(err error, code string) bool {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
-		return pgErr.Code == pgErrUniqueViolation
+		return pgErr.Code == code
 	}
 	return false
 }
@@ -0,0 +1,221 @@
+package dbx
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// InsertBuilder builds "INSERT INTO ... VALUES ..." queries with optional
+// ON CONFLICT and RETURNING clauses.
+type InsertBuilder struct {
+	table     string
+	columns   []string
+	rows      [][]any
+	conflict  []string
+	doNothing bool
+	doUpdate  bool
+	update    []string
+	returning []string
+	errs      []error
+}
+
+// NewInsertBuilder initializes the INSERT builder for the given table.
+func NewInsertBuilder(table string) *InsertBuilder {
+	return &InsertBuilder{
+		table:     table,
+		columns:   []string{},
+		rows:      [][]any{},
+		conflict:  []string{},
+		update:    []string{},
+		returning: []string{},
+	}
+}
+
+// SetColumn adds a column and its value to a single row insert.
+func (b *InsertBuilder) SetColumn(column string, value any) {
+	if column == "" {
+		return
+	}
+
+	if len(b.rows) > 1 {
+		b.errs = append(b.errs, fmt.Errorf("dbx: cannot set column %q on a multi-row insert", column))
+		return
+	}
+
+	if len(b.rows) == 0 {
+		b.rows = append(b.rows, []any{})
+	}
+
+	b.columns = append(b.columns, column)
+	b.rows[0] = append(b.rows[0], value)
+}
+
+// SetColumns sets the columns for rows added with AddRow.
+func (b *InsertBuilder) SetColumns(columns ...string) {
+	b.columns = columns
+}
+
+// AddRow adds a row of values in the same order as the columns.
+func (b *InsertBuilder) AddRow(values ...any) {
+	if len(values) != len(b.columns) {
+		b.errs = append(b.errs, fmt.Errorf("dbx: row has %d values but insert has %d columns", len(values), len(b.columns)))
+		return
+	}
+
+	b.rows = append(b.rows, values)
+}
+
+// AddStruct adds a row from a struct using its `db` tags.
+// Every exported field needs a `db` tag, `db:"-"` skips it.
+// The first struct sets the columns, later structs must produce the same columns.
+// Columns listed in omit (e.g. a generated "id") are skipped.
+func (b *InsertBuilder) AddStruct(v any, omit ...string) {
+	columns, values, err := structColumns(v, omit...)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return
+	}
+
+	if len(b.rows) == 0 {
+		b.columns = columns
+	} else if !slices.Equal(b.columns, columns) {
+		b.errs = append(b.errs, fmt.Errorf("dbx: struct columns %v do not match insert columns %v", columns, b.columns))
+		return
+	}
+
+	b.rows = append(b.rows, values)
+}
+
+// OnConflictDoNothing adds "ON CONFLICT (columns) DO NOTHING".
+// Without columns it adds "ON CONFLICT DO NOTHING".
+func (b *InsertBuilder) OnConflictDoNothing(columns ...string) {
+	b.conflict = columns
+	b.doNothing = true
+	b.doUpdate = false
+}
+
+// OnConflictDoUpdate adds "ON CONFLICT (columns) DO UPDATE SET col = EXCLUDED.col, ...".
+// Without update columns, every inserted column except the conflict columns is updated.
+func (b *InsertBuilder) OnConflictDoUpdate(conflict []string, update ...string) {
+	if len(conflict) == 0 {
+		b.errs = append(b.errs, errors.New("dbx: ON CONFLICT DO UPDATE requires conflict columns"))
+		return
+	}
+
+	b.conflict = conflict
+	b.update = update
+	b.doUpdate = true
+	b.doNothing = false
+}
+
+// Returning adds a RETURNING clause.
+func (b *InsertBuilder) Returning(columns ...string) {
+	b.returning = append(b.returning, columns...)
+}
+
+// Build returns the final SQL query and args.
+func (b *InsertBuilder) Build() (string, []any, error) {
+	if len(b.errs) > 0 {
+		return "", nil, errors.Join(b.errs...)
+	}
+
+	if b.table == "" {
+		return "", nil, errors.New("dbx: insert requires a table")
+	}
+
+	if len(b.columns) == 0 || len(b.rows) == 0 {
+		return "", nil, fmt.Errorf("dbx: insert into %s has no values", b.table)
+	}
+
+	// The columns can be changed after rows were added, e.g. by SetColumns.
+	for i, row := range b.rows {
+		if len(row) != len(b.columns) {
+			return "", nil, fmt.Errorf("dbx: row %d has %d values but insert into %s has %d columns", i+1, len(row), b.table, len(b.columns))
+		}
+	}
+
+	var sb strings.Builder
+	args := make([]any, 0, len(b.columns)*len(b.rows))
+
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(b.table)
+	sb.WriteString(" (")
+	sb.WriteString(strings.Join(b.columns, ", "))
+	sb.WriteString(") VALUES ")
+
+	for i, row := range b.rows {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		placeholders := make([]string, len(row))
+		for j, value := range row {
+			args = append(args, value)
+			placeholders[j] = fmt.Sprintf("$%d", len(args))
+		}
+
+		sb.WriteString("(")
+		sb.WriteString(strings.Join(placeholders, ", "))
+		sb.WriteString(")")
+	}
+
+	sb.WriteString(b.conflictClause())
+
+	if len(b.returning) > 0 {
+		sb.WriteString(" RETURNING ")
+		sb.WriteString(strings.Join(b.returning, ", "))
+	}
+
+	return sb.String(), args, nil
+}
+
+func (b *InsertBuilder) conflictClause() string {
+	return conflictClause(b.columns, b.conflict, b.update, b.doNothing, b.doUpdate)
+}
+
+// conflictClause renders the ON CONFLICT clause for an insert into columns.
+func conflictClause(columns, conflict, update []string, doNothing, doUpdate bool) string {
+	if !doNothing && !doUpdate {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(" ON CONFLICT")
+
+	if len(conflict) > 0 {
+		sb.WriteString(" (")
+		sb.WriteString(strings.Join(conflict, ", "))
+		sb.WriteString(")")
+	}
+
+	if doNothing {
+		sb.WriteString(" DO NOTHING")
+		return sb.String()
+	}
+
+	if len(update) == 0 {
+		for _, column := range columns {
+			if !slices.Contains(conflict, column) {
+				update = append(update, column)
+			}
+		}
+	}
+
+	// Nothing left to update, so the conflicting row is kept as is.
+	if len(update) == 0 {
+		sb.WriteString(" DO NOTHING")
+		return sb.String()
+	}
+
+	assignments := make([]string, len(update))
+	for i, column := range update {
+		assignments[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+
+	sb.WriteString(" DO UPDATE SET ")
+	sb.WriteString(strings.Join(assignments, ", "))
+
+	return sb.String()
+}
@@ -0,0 +1,145 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pgxdecimal "github.com/jackc/pgx-shopspring-decimal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Option configures the pool created by New.
+type Option func(*options)
+
+type options struct {
+	maxConns          int32
+	minConns          int32
+	maxConnLifetime   time.Duration
+	maxConnIdleTime   time.Duration
+	healthCheckPeriod time.Duration
+	statementTimeout  time.Duration
+	applicationName   string
+	tracer            pgx.QueryTracer
+	registerTypes     []func(m *pgtype.Map)
+	loadTypes         []string
+	afterConnect      []func(ctx context.Context, conn *pgx.Conn) error
+}
+
+// WithMaxConns sets the maximum size of the pool.
+func WithMaxConns(n int32) Option {
+	return func(o *options) { o.maxConns = n }
+}
+
+// WithMinConns sets the minimum number of connections kept open by the pool.
+func WithMinConns(n int32) Option {
+	return func(o *options) { o.minConns = n }
+}
+
+// WithMaxConnLifetime sets how long a connection is used before it is closed.
+func WithMaxConnLifetime(d time.Duration) Option {
+	return func(o *options) { o.maxConnLifetime = d }
+}
+
+// WithMaxConnIdleTime sets how long an idle connection is kept before it is closed.
+func WithMaxConnIdleTime(d time.Duration) Option {
+	return func(o *options) { o.maxConnIdleTime = d }
+}
+
+// WithHealthCheckPeriod sets how often idle connections are checked.
+func WithHealthCheckPeriod(d time.Duration) Option {
+	return func(o *options) { o.healthCheckPeriod = d }
+}
+
+// WithStatementTimeout sets the PostgreSQL statement_timeout of every connection.
+func WithStatementTimeout(d time.Duration) Option {
+	return func(o *options) { o.statementTimeout = d }
+}
+
+// WithApplicationName sets the PostgreSQL application_name of every connection,
+// which shows up in pg_stat_activity and the server logs.
+func WithApplicationName(name string) Option {
+	return func(o *options) { o.applicationName = name }
+}
+
+// WithTracer replaces the default tracer that logs SQL queries, e.g. with a configured NewTracer.
+// The tracer is also used for batches, COPY, prepare, connect and pool acquire when it implements
+// the matching pgx and pgxpool tracer interfaces, as Tracer does. A nil tracer disables tracing.
+func WithTracer(tracer pgx.QueryTracer) Option {
+	return func(o *options) { o.tracer = tracer }
+}
+
+// WithRegisterTypes registers custom types on the type map of every connection,
+// e.g. a codec for a Go type. `decimal.Decimal` is always registered.
+func WithRegisterTypes(fn func(m *pgtype.Map)) Option {
+	return func(o *options) { o.registerTypes = append(o.registerTypes, fn) }
+}
+
+// WithLoadTypes loads database defined types (enums, composites, domains and their arrays)
+// by name on every connection, e.g. WithLoadTypes("user_role", "_user_role").
+func WithLoadTypes(names ...string) Option {
+	return func(o *options) { o.loadTypes = append(o.loadTypes, names...) }
+}
+
+// WithAfterConnect runs fn on every new connection after the types are registered.
+func WithAfterConnect(fn func(ctx context.Context, conn *pgx.Conn) error) Option {
+	return func(o *options) { o.afterConnect = append(o.afterConnect, fn) }
+}
+
+// apply sets the options on the parsed pool config.
+func (o *options) apply(config *pgxpool.Config) {
+	if o.maxConns > 0 {
+		config.MaxConns = o.maxConns
+	}
+	if o.minConns > 0 {
+		config.MinConns = o.minConns
+	}
+	if o.maxConnLifetime > 0 {
+		config.MaxConnLifetime = o.maxConnLifetime
+	}
+	if o.maxConnIdleTime > 0 {
+		config.MaxConnIdleTime = o.maxConnIdleTime
+	}
+	if o.healthCheckPeriod > 0 {
+		config.HealthCheckPeriod = o.healthCheckPeriod
+	}
+
+	if config.ConnConfig.RuntimeParams == nil {
+		config.ConnConfig.RuntimeParams = map[string]string{}
+	}
+	if o.statementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = fmt.Sprintf("%d", o.statementTimeout.Milliseconds())
+	}
+	if o.applicationName != "" {
+		config.ConnConfig.RuntimeParams["application_name"] = o.applicationName
+	}
+
+	config.ConnConfig.Tracer = o.tracer
+
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		// Register `pgxdecimal` so that we can use `decimal.Decimal` for values while scaning or inserting records.
+		pgxdecimal.Register(conn.TypeMap())
+
+		for _, register := range o.registerTypes {
+			register(conn.TypeMap())
+		}
+
+		if len(o.loadTypes) > 0 {
+			types, err := conn.LoadTypes(ctx, o.loadTypes)
+			if err != nil {
+				return fmt.Errorf("load types: %w", err)
+			}
+			conn.TypeMap().RegisterTypes(types)
+		}
+
+		for _, fn := range o.afterConnect {
+			if err := fn(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
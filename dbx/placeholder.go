@@ -0,0 +1,180 @@
+package dbx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// rewritePlaceholders calls fn for every "$N" placeholder in sql and replaces it
+// with the returned string. Placeholders inside quoted strings and identifiers are left as is.
+func rewritePlaceholders(sql string, fn func(n int) string) string {
+	var sb strings.Builder
+	sb.Grow(len(sql))
+
+	_ = scanUnquoted(sql, &sb, func(i int) int {
+		if sql[i] != '$' || i+1 >= len(sql) || !isDigit(sql[i+1]) {
+			return 0
+		}
+
+		j := i + 1
+		for j < len(sql) && isDigit(sql[j]) {
+			j++
+		}
+		n, _ := strconv.Atoi(sql[i+1 : j])
+		sb.WriteString(fn(n))
+		return j - i
+	})
+
+	return sb.String()
+}
+
+// placeholderNumbers returns the "$N" numbers referenced in sql.
+func placeholderNumbers(sql string) []int {
+	numbers := []int{}
+	rewritePlaceholders(sql, func(n int) string {
+		numbers = append(numbers, n)
+		return ""
+	})
+	return numbers
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// bindPlaceholders rewrites "?" or "@name" placeholders in sql into "$N" numbers starting
+// at argNum and returns the args in placeholder order.
+//
+//   - "@name" is used when args is a single pgx.NamedArgs.
+//     Each distinct name is bound once, no matter how often it is referenced.
+//   - "?" is used when args are given and sql has no "$N" placeholders.
+//     Write "??" for a literal "?", e.g. the jsonb "??" / "??|" operators.
+//
+// Otherwise sql is expected to use "$N" numbers and is returned as is.
+func bindPlaceholders(sql string, argNum int, args []any) (string, []any, error) {
+	if len(args) == 1 {
+		if named, ok := args[0].(pgx.NamedArgs); ok {
+			return bindNamed(sql, argNum, named)
+		}
+	}
+
+	if len(args) == 0 || len(placeholderNumbers(sql)) > 0 {
+		return sql, args, nil
+	}
+
+	return bindPositional(sql, argNum, args)
+}
+
+func bindPositional(sql string, argNum int, args []any) (string, []any, error) {
+	var sb strings.Builder
+	count := 0
+
+	err := scanUnquoted(sql, &sb, func(i int) int {
+		if sql[i] != '?' {
+			return 0
+		}
+
+		if i+1 < len(sql) && sql[i+1] == '?' {
+			sb.WriteByte('?')
+			return 2
+		}
+
+		sb.WriteString("$" + strconv.Itoa(argNum+count))
+		count++
+		return 1
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if count != len(args) {
+		return "", nil, fmt.Errorf("%w: %q has %d placeholders but %d args were given", ErrArgCount, sql, count, len(args))
+	}
+
+	return sb.String(), args, nil
+}
+
+func bindNamed(sql string, argNum int, named pgx.NamedArgs) (string, []any, error) {
+	var sb strings.Builder
+	numbers := map[string]int{}
+	args := []any{}
+	var missing error
+
+	err := scanUnquoted(sql, &sb, func(i int) int {
+		if sql[i] != '@' || i+1 >= len(sql) || !isIdentStart(sql[i+1]) {
+			return 0
+		}
+
+		j := i + 1
+		for j < len(sql) && (isIdentStart(sql[j]) || isDigit(sql[j])) {
+			j++
+		}
+		name := sql[i+1 : j]
+
+		n, ok := numbers[name]
+		if !ok {
+			value, exists := named[name]
+			if !exists && missing == nil {
+				missing = fmt.Errorf("%w: %q references @%s which was not given", ErrArgCount, sql, name)
+			}
+
+			n = argNum + len(args)
+			numbers[name] = n
+			args = append(args, value)
+		}
+
+		sb.WriteString("$" + strconv.Itoa(n))
+		return j - i
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if missing != nil {
+		return "", nil, missing
+	}
+
+	return sb.String(), args, nil
+}
+
+// scanUnquoted copies sql into sb, calling fn for every byte outside quotes.
+// fn returns how many bytes it consumed (after writing its own output) or 0 to copy the byte.
+func scanUnquoted(sql string, sb *strings.Builder, fn func(i int) int) error {
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			sb.WriteByte(c)
+			continue
+		}
+
+		if c == '\'' || c == '"' {
+			quote = c
+			sb.WriteByte(c)
+			continue
+		}
+
+		if consumed := fn(i); consumed > 0 {
+			i += consumed - 1
+			continue
+		}
+
+		sb.WriteByte(c)
+	}
+
+	if quote != 0 {
+		return fmt.Errorf("dbx: unterminated quote in %q", sql)
+	}
+
+	return nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
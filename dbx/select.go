@@ -0,0 +1,122 @@
+package dbx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewSelectBuilder initializes the SQL builder with "SELECT columns FROM from".
+// Unlike NewSQLBuilder, the select list can be extended with AddSelect,
+// AddAggregate and AddWindow. Without any columns it selects "*".
+func NewSelectBuilder(from string, columns ...string) *SQLBuilder {
+	b := NewSQLBuilder("")
+	b.from = from
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// AddSelect adds an expression to the select list, e.g. "u.id" or
+// "COUNT(*) FILTER (WHERE status = ?) AS pending". See AppendWhere for placeholders.
+// It requires a builder created with NewSelectBuilder.
+func (b *SQLBuilder) AddSelect(expr string, args ...any) {
+	if b.from == "" {
+		b.errs = append(b.errs, fmt.Errorf("dbx: cannot add %q to the select list of a raw base SQL, use NewSelectBuilder", expr))
+		return
+	}
+
+	expr, args, ok := b.bind(expr, args)
+	if !ok {
+		return
+	}
+
+	b.columns = append(b.columns, expr)
+	b.args = append(b.args, args...)
+	b.argNum += len(args)
+}
+
+// AddAggregate adds an aggregate to the select list: "fn(expr) AS alias",
+// e.g. AddAggregate("SUM", "amount", "total").
+func (b *SQLBuilder) AddAggregate(fn, expr, alias string) {
+	b.AddSelect(withAlias(fmt.Sprintf("%s(%s)", fn, expr), alias))
+}
+
+// AddWindow adds a window function to the select list:
+// "expr OVER (PARTITION BY partitionBy ORDER BY orderBy) AS alias",
+// e.g. AddWindow("ROW_NUMBER()", []string{"user_id"}, []string{"created_at DESC"}, "rn").
+func (b *SQLBuilder) AddWindow(expr string, partitionBy, orderBy []string, alias string) {
+	over := []string{}
+	if len(partitionBy) > 0 {
+		over = append(over, "PARTITION BY "+strings.Join(partitionBy, ", "))
+	}
+	if len(orderBy) > 0 {
+		over = append(over, "ORDER BY "+strings.Join(orderBy, ", "))
+	}
+
+	b.AddSelect(withAlias(fmt.Sprintf("%s OVER (%s)", expr, strings.Join(over, " ")), alias))
+}
+
+// SetDistinct makes the query "SELECT DISTINCT", or "SELECT DISTINCT ON (on)" when columns are given.
+// With a raw base SQL, the base must start with "SELECT".
+func (b *SQLBuilder) SetDistinct(on ...string) {
+	if b.from == "" && !hasSelectPrefix(b.base.String()) {
+		b.errs = append(b.errs, fmt.Errorf("dbx: DISTINCT requires a base SQL starting with SELECT"))
+		return
+	}
+
+	b.distinct = true
+	b.distinctOn = on
+}
+
+// writeBase writes the base SQL or the "SELECT ... FROM ..." of NewSelectBuilder.
+func (b *SQLBuilder) writeBase(sb *strings.Builder) {
+	if b.from == "" {
+		base := b.base.String()
+		if !b.distinct {
+			sb.WriteString(base)
+			return
+		}
+
+		// Insert DISTINCT right after the leading SELECT of the raw base.
+		base = strings.TrimLeft(base, " \t\r\n")
+		sb.WriteString(base[:len("SELECT")])
+		sb.WriteString(b.distinctClause())
+		sb.WriteString(base[len("SELECT"):])
+		return
+	}
+
+	columns := b.columns
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+
+	sb.WriteString("SELECT")
+	sb.WriteString(b.distinctClause())
+	sb.WriteString(" ")
+	sb.WriteString(strings.Join(columns, ", "))
+	sb.WriteString(" FROM ")
+	sb.WriteString(b.from)
+}
+
+func (b *SQLBuilder) distinctClause() string {
+	if !b.distinct {
+		return ""
+	}
+	if len(b.distinctOn) > 0 {
+		return fmt.Sprintf(" DISTINCT ON (%s)", strings.Join(b.distinctOn, ", "))
+	}
+	return " DISTINCT"
+}
+
+func hasSelectPrefix(sql string) bool {
+	sql = strings.TrimLeft(sql, " \t\r\n")
+	return len(sql) > len("SELECT") &&
+		strings.EqualFold(sql[:len("SELECT")], "SELECT") &&
+		strings.ContainsRune(" \t\r\n", rune(sql[len("SELECT")]))
+}
+
+func withAlias(expr, alias string) string {
+	if alias == "" {
+		return expr
+	}
+	return fmt.Sprintf("%s AS %s", expr, alias)
+}
@@ -1,7 +1,10 @@
 package dbx
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -14,11 +17,18 @@ type DateRange struct {
 type Operator string
 
 const (
-	OperatorGTE Operator = "gte"
-	OperatorGT  Operator = "gt"
-	OperatorLTE Operator = "lte"
-	OperatorLT  Operator = "lt"
-	OperatorEQ  Operator = "eq"
+	OperatorGTE             Operator = "gte"
+	OperatorGT              Operator = "gt"
+	OperatorLTE             Operator = "lte"
+	OperatorLT              Operator = "lt"
+	OperatorEQ              Operator = "eq"
+	OperatorNEQ             Operator = "neq"
+	OperatorIN              Operator = "in"
+	OperatorNotIN           Operator = "nin"
+	OperatorIsNull          Operator = "null"
+	OperatorIsNotNull       Operator = "notnull"
+	OperatorJSONContains    Operator = "json_contains"
+	OperatorJSONContainedBy Operator = "json_contained_by"
 )
 
 func (o *Operator) String() string {
@@ -37,18 +47,27 @@ func (o *Operator) SQL() string {
 		return "<"
 	case OperatorEQ:
 		return "="
+	case OperatorNEQ:
+		return "<>"
+	case OperatorIN:
+		return "IN"
+	case OperatorNotIN:
+		return "NOT IN"
+	case OperatorIsNull:
+		return "IS NULL"
+	case OperatorIsNotNull:
+		return "IS NOT NULL"
+	case OperatorJSONContains:
+		return "@>"
+	case OperatorJSONContainedBy:
+		return "<@"
 	default:
 		return ""
 	}
 }
 
 func (o *Operator) IsValid() bool {
-	switch *o {
-	case OperatorGTE, OperatorGT, OperatorLTE, OperatorLT, OperatorEQ:
-		return true
-	default:
-		return false
-	}
+	return o.SQL() != ""
 }
 
 // parseOperator will check if the string provided is `Operator` enum
@@ -61,25 +80,69 @@ func parseOperator(o Operator) string {
 
 	// Allow raw SQL operators directly
 	switch o.String() {
-	case "=", "!=", "<", "<=", ">", ">=":
+	case "=", "!=", "<>", "<", "<=", ">", ">=", "@>", "<@":
 		return o.String()
 	}
 
 	return "" // invalid
 }
 
-// TODO: Add a methoda in SQLBuilder to add "JOINS".
-
 type SQLBuilder struct {
-	base    strings.Builder
-	set     []string
-	where   []string
-	order   string
-	limit   string
-	offset  string
-	groupBy []string
-	args    []any
-	argNum  int
+	ctes       []string
+	base       strings.Builder
+	from       string
+	columns    []string
+	distinct   bool
+	distinctOn []string
+	joins      []string
+	set        []string
+	where      []string
+	order      []sortClause
+	tiebreaker string
+	countExpr  string
+	limit      string
+	offset     string
+	groupBy    []string
+	having     []string
+	returning  []string
+	fullTable  bool
+	args       []any
+	argNum     int
+	errs       []error
+}
+
+// sortClause is a single "column ASC|DESC [NULLS FIRST|LAST]" entry of ORDER BY.
+type sortClause struct {
+	column    string
+	direction string
+	nulls     string
+
+	// args are referenced by column as "$1", "$2", ... and are only bound when the
+	// ORDER BY is rendered, so that replacing the sorting does not leave them unused.
+	args []any
+}
+
+// newSortClause normalizes the order and nulls placement of a sort clause.
+// An unknown order sorts ascending and an unknown nulls value leaves the PostgreSQL default.
+func newSortClause(column, order, nulls string) sortClause {
+	order = strings.ToUpper(order)
+	if order != "ASC" && order != "DESC" {
+		order = "ASC"
+	}
+
+	nulls = strings.ToUpper(nulls)
+	if nulls != "FIRST" && nulls != "LAST" {
+		nulls = ""
+	}
+
+	return sortClause{column: column, direction: order, nulls: nulls}
+}
+
+func (c sortClause) String() string {
+	if c.nulls != "" {
+		return fmt.Sprintf("%s %s NULLS %s", c.column, c.direction, c.nulls)
+	}
+	return fmt.Sprintf("%s %s", c.column, c.direction)
 }
 
 // NewSQLBuilder initializes the SQL builder with a base SELECT clause.
@@ -88,18 +151,162 @@ func NewSQLBuilder(baseSQL string) *SQLBuilder {
 	sb.WriteString(baseSQL)
 
 	return &SQLBuilder{
-		base:    sb,
-		set:     []string{},
-		where:   []string{},
-		groupBy: []string{},
-		args:    []any{},
-		argNum:  1,
+		ctes:       []string{},
+		base:       sb,
+		columns:    []string{},
+		distinctOn: []string{},
+		joins:      []string{},
+		set:        []string{},
+		where:      []string{},
+		order:      []sortClause{},
+		groupBy:    []string{},
+		having:     []string{},
+		returning:  []string{},
+		args:       []any{},
+		argNum:     1,
+	}
+}
+
+// Clone returns a copy of the builder that can be changed independently,
+// e.g. to add a different page to the same base query.
+func (b *SQLBuilder) Clone() *SQLBuilder {
+	c := NewSQLBuilder(b.base.String())
+	c.ctes = slices.Clone(b.ctes)
+	c.from = b.from
+	c.columns = slices.Clone(b.columns)
+	c.distinct = b.distinct
+	c.distinctOn = slices.Clone(b.distinctOn)
+	c.joins = slices.Clone(b.joins)
+	c.set = slices.Clone(b.set)
+	c.where = slices.Clone(b.where)
+	c.order = slices.Clone(b.order)
+	c.tiebreaker = b.tiebreaker
+	c.countExpr = b.countExpr
+	c.limit = b.limit
+	c.offset = b.offset
+	c.groupBy = slices.Clone(b.groupBy)
+	c.having = slices.Clone(b.having)
+	c.returning = slices.Clone(b.returning)
+	c.fullTable = b.fullTable
+	c.args = slices.Clone(b.args)
+	c.argNum = b.argNum
+	c.errs = slices.Clone(b.errs)
+	return c
+}
+
+// NewUpdateBuilder initializes the SQL builder with "UPDATE table".
+// Build refuses to render it without WHERE conditions, see AllowFullTable.
+func NewUpdateBuilder(table string) *SQLBuilder {
+	return NewSQLBuilder("UPDATE " + table)
+}
+
+// NewDeleteBuilder initializes the SQL builder with "DELETE FROM table".
+// Build refuses to render it without WHERE conditions, see AllowFullTable.
+func NewDeleteBuilder(table string) *SQLBuilder {
+	return NewSQLBuilder("DELETE FROM " + table)
+}
+
+// AllowFullTable allows an UPDATE or DELETE to be built without WHERE conditions.
+// Without it, Build returns ErrMissingWhere so that a filter skipped because of
+// a missing value can never update or delete every row of the table.
+// A WHERE written in the base SQL, e.g. "DELETE FROM sessions WHERE expiry < now()", counts as a condition.
+func (b *SQLBuilder) AllowFullTable() {
+	b.fullTable = true
+}
+
+// Returning adds a RETURNING clause for UPDATE and DELETE queries.
+func (b *SQLBuilder) Returning(columns ...string) {
+	if !b.isMutation() {
+		b.errs = append(b.errs, errors.New("dbx: RETURNING requires an UPDATE or DELETE base SQL"))
+		return
+	}
+
+	b.returning = append(b.returning, columns...)
+}
+
+// isMutation reports whether the base SQL is an UPDATE or DELETE,
+// including one after a leading "WITH ... AS (...)".
+func (b *SQLBuilder) isMutation() bool {
+	keyword := statementKeyword(b.base.String())
+	return keyword == "UPDATE" || keyword == "DELETE"
+}
+
+// statementKeyword returns the upper-cased keyword of the main statement of sql,
+// e.g. "DELETE" for "WITH old AS (SELECT ...) DELETE FROM ...".
+// The CTEs of a leading WITH are skipped by ignoring everything within parentheses.
+func statementKeyword(sql string) string {
+	keyword := ""
+	with := false
+
+	scanTopLevelWords(sql, func(word string) bool {
+		if !with {
+			if word != "WITH" {
+				keyword = word
+				return false
+			}
+			with = true
+			return true
+		}
+
+		switch word {
+		case "SELECT", "INSERT", "UPDATE", "DELETE", "MERGE", "VALUES", "TABLE":
+			keyword = word
+			return false
+		}
+		return true
+	})
+
+	return keyword
+}
+
+// hasTopLevelWhere reports whether sql has a WHERE clause of its own,
+// not one within parentheses such as a CTE or sub-query.
+func hasTopLevelWhere(sql string) bool {
+	found := false
+	scanTopLevelWords(sql, func(word string) bool {
+		found = word == "WHERE"
+		return !found
+	})
+	return found
+}
+
+// scanTopLevelWords calls fn with every upper-cased word of sql outside quotes and parentheses,
+// until fn returns false.
+func scanTopLevelWords(sql string, fn func(word string) bool) {
+	var quote byte
+	depth := 0
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && isIdentStart(c):
+			j := i + 1
+			for j < len(sql) && (isIdentStart(sql[j]) || isDigit(sql[j])) {
+				j++
+			}
+			if !fn(strings.ToUpper(sql[i:j])) {
+				return
+			}
+			i = j - 1
+		}
 	}
 }
 
 // SetColumn adds a SET clause for UPDATE queries: "SET column = $N"
 func (b *SQLBuilder) SetColumn(column string, value any) {
 	if column == "" {
+		b.addErr(ErrEmptyColumn, "SET")
 		return
 	}
 	assignment := fmt.Sprintf("%s = $%d", column, b.nextArg())
@@ -107,93 +314,161 @@ func (b *SQLBuilder) SetColumn(column string, value any) {
 	b.args = append(b.args, value)
 }
 
-// AddCompareFilter adds a single condition like "column = $N", "column >= $N"
-func (b *SQLBuilder) AddCompareFilter(column string, operator Operator, value any) {
-	if column == "" || operator == "" || value == nil {
+// SetExpr adds a SET clause for UPDATE queries with a custom expression,
+// e.g. "count = count + ?" or "tags = array_append(tags, @tag)", see AppendWhere for placeholders.
+func (b *SQLBuilder) SetExpr(assignment string, args ...any) {
+	assignment, args, ok := b.bind(assignment, args)
+	if !ok {
 		return
 	}
 
-	operatorSQL := parseOperator(operator)
+	b.set = append(b.set, assignment)
+	b.args = append(b.args, args...)
+	b.argNum += len(args)
+}
 
-	condition := fmt.Sprintf("%s %s $%d", column, operatorSQL, b.nextArg())
-	b.where = append(b.where, condition)
-	b.args = append(b.args, value)
+// InnerJoin adds "INNER JOIN table ON condition".
+// The condition may reference args with placeholders, see AppendWhere.
+func (b *SQLBuilder) InnerJoin(table, condition string, args ...any) {
+	b.addJoin("INNER JOIN", table, condition, args...)
 }
 
-// AddBetweenFilter adds a BETWEEN condition like "column BETWEEN $N AND $N+1"
-func (b *SQLBuilder) AddBetweenFilter(column string, from, to any) {
-	if from == nil || to == nil {
+// LeftJoin adds "LEFT JOIN table ON condition".
+// The condition may reference args with placeholders, see AppendWhere.
+func (b *SQLBuilder) LeftJoin(table, condition string, args ...any) {
+	b.addJoin("LEFT JOIN", table, condition, args...)
+}
+
+// RightJoin adds "RIGHT JOIN table ON condition".
+// The condition may reference args with placeholders, see AppendWhere.
+func (b *SQLBuilder) RightJoin(table, condition string, args ...any) {
+	b.addJoin("RIGHT JOIN", table, condition, args...)
+}
+
+// CrossJoin adds "CROSS JOIN table".
+func (b *SQLBuilder) CrossJoin(table string) {
+	b.addJoin("CROSS JOIN", table, "")
+}
+
+// addJoin adds a JOIN clause with an optional ON condition (private helper)
+func (b *SQLBuilder) addJoin(kind, table, condition string, args ...any) {
+	if table == "" {
+		b.addErr(ErrEmptyColumn, kind)
 		return
 	}
-	condition := fmt.Sprintf("%s BETWEEN $%d AND $%d", column, b.argNum, b.argNum+1)
-	b.where = append(b.where, condition)
-	b.args = append(b.args, from, to)
-	b.argNum += 2
-}
 
-// AddArrayFilter adds a condition like "column = ANY($N)" for array values
-func (b *SQLBuilder) AddArrayFilter(column string, values []any) {
-	if len(values) == 0 {
+	condition, args, ok := b.bind(condition, args)
+	if !ok {
 		return
 	}
-	condition := fmt.Sprintf("%s = ANY($%d)", column, b.nextArg())
-	b.where = append(b.where, condition)
-	b.args = append(b.args, values)
+
+	join := fmt.Sprintf("%s %s", kind, table)
+	if condition != "" {
+		join = fmt.Sprintf("%s ON %s", join, condition)
+	}
+
+	b.joins = append(b.joins, join)
+	b.args = append(b.args, args...)
+	b.argNum += len(args)
 }
 
-// addLikeFilter adds a LIKE condition with custom pattern (private helper)
-func (b *SQLBuilder) addLikeFilter(column string, pattern string, caseSensitive bool) {
-	if column == "" || pattern == "" {
+// AddCondition adds a condition tree built with And, Or, Not and the filter conditions.
+// Conditions added separately are still joined with " AND ".
+func (b *SQLBuilder) AddCondition(condition Condition) {
+	if condition == nil {
 		return
 	}
-
-	operator := "LIKE"
-	if !caseSensitive {
-		operator = "ILIKE"
+	if sql := condition.build(b); sql != "" {
+		b.where = append(b.where, sql)
 	}
+}
+
+// AddCompareFilter adds a single condition like "column = $N", "column >= $N"
+func (b *SQLBuilder) AddCompareFilter(column string, operator Operator, value any) {
+	b.AddCondition(Compare(column, operator, value))
+}
+
+// AddBetweenFilter adds a BETWEEN condition like "column BETWEEN $N AND $N+1"
+func (b *SQLBuilder) AddBetweenFilter(column string, from, to any) {
+	b.AddCondition(Between(column, from, to))
+}
 
-	condition := fmt.Sprintf("%s %s $%d", column, operator, b.nextArg())
-	b.where = append(b.where, condition)
-	b.args = append(b.args, pattern)
+// AddArrayFilter adds a condition like "column = ANY($N)" for array values
+func (b *SQLBuilder) AddArrayFilter(column string, values []any) {
+	b.AddCondition(Array(column, values))
 }
 
 // AddStartsWithFilter adds a LIKE condition for prefix matching ("value%")
 func (b *SQLBuilder) AddStartsWithFilter(column string, value string, caseSensitive bool) {
-	if column == "" || value == "" {
-		return
-	}
-	b.addLikeFilter(column, value+"%", caseSensitive)
+	b.AddCondition(StartsWith(column, value, caseSensitive))
 }
 
 // AddEndsWithFilter adds a LIKE condition for suffix matching ("%value")
 func (b *SQLBuilder) AddEndsWithFilter(column string, value string, caseSensitive bool) {
-	if column == "" || value == "" {
-		return
-	}
-	b.addLikeFilter(column, "%"+value, caseSensitive)
+	b.AddCondition(EndsWith(column, value, caseSensitive))
 }
 
 // AddContainsFilter adds a LIKE condition for substring matching ("%value%")
 func (b *SQLBuilder) AddContainsFilter(column string, value string, caseSensitive bool) {
-	if column == "" || value == "" {
-		return
-	}
-	b.addLikeFilter(column, "%"+value+"%", caseSensitive)
+	b.AddCondition(Contains(column, value, caseSensitive))
 }
 
 func (b *SQLBuilder) AddGroupBy(columns ...string) {
 	b.groupBy = append(b.groupBy, columns...)
 }
 
-// AddSorting adds an ORDER BY clause.
+// AddHaving adds a HAVING condition with placeholders, see AppendWhere,
+// e.g. "COUNT(*) > ?". Conditions are joined with " AND ".
+func (b *SQLBuilder) AddHaving(condition string, args ...any) {
+	condition, args, ok := b.bind(condition, args)
+	if !ok {
+		return
+	}
+
+	b.having = append(b.having, "("+condition+")")
+	b.args = append(b.args, args...)
+	b.argNum += len(args)
+}
+
+// AddHavingCondition adds a condition tree to the HAVING clause,
+// e.g. Compare("SUM(amount)", OperatorGTE, 100).
+func (b *SQLBuilder) AddHavingCondition(condition Condition) {
+	if condition == nil {
+		return
+	}
+	if sql := condition.build(b); sql != "" {
+		b.having = append(b.having, sql)
+	}
+}
+
+// AddSorting adds a column to the ORDER BY clause.
+// Each call adds a key after the previous ones, e.g. "ORDER BY pinned DESC, created_at DESC".
 func (b *SQLBuilder) AddSorting(field, order string) {
+	b.AddSortingNulls(field, order, "")
+}
+
+// AddSortingNulls adds a column to the ORDER BY clause with "NULLS FIRST" or "NULLS LAST".
+// An empty or unknown nulls value leaves the PostgreSQL default.
+func (b *SQLBuilder) AddSortingNulls(field, order, nulls string) {
 	if field == "" {
 		return
 	}
-	if strings.ToUpper(order) != "ASC" && strings.ToUpper(order) != "DESC" {
-		order = "ASC"
-	}
-	b.order = fmt.Sprintf("ORDER BY %s %s", field, order)
+
+	b.order = append(b.order, newSortClause(field, order, nulls))
+}
+
+// addSortingExpr adds a sort expression whose args are referenced as "$1", "$2", ...,
+// e.g. "similarity(name, $1)". The args are bound when the query is built.
+func (b *SQLBuilder) addSortingExpr(expr, order string, args ...any) {
+	clause := newSortClause(expr, order, "")
+	clause.args = args
+	b.order = append(b.order, clause)
+}
+
+// SetTiebreaker sets a unique column that is always sorted on last ("column ASC")
+// unless already sorted on, so that rows with equal sort keys have a stable order.
+func (b *SQLBuilder) SetTiebreaker(column string) {
+	b.tiebreaker = column
 }
 
 // AddPagination adds LIMIT/OFFSET clauses.
@@ -206,10 +481,127 @@ func (b *SQLBuilder) AddPagination(limit, offset int) {
 	}
 }
 
+// AddKeysetPagination adds keyset (cursor) pagination over the sort key tuple in columns.
+// With values it adds a row-value comparison like "(created_at, id) > ($N, $N+1)",
+// without values (first page) it only orders and limits.
+// It sets "ORDER BY" for all columns in order (flipped when paging backward)
+// and "LIMIT limit+1" so that the caller can tell if there are more rows.
+func (b *SQLBuilder) AddKeysetPagination(columns []string, order string, values []any, backward bool, limit int) {
+	if len(columns) == 0 {
+		b.addErr(ErrEmptyColumn, "keyset pagination")
+		return
+	}
+	if len(values) > 0 && len(values) != len(columns) {
+		b.errs = append(b.errs, fmt.Errorf("%w: keyset pagination has %d values for %d columns", ErrArgCount, len(values), len(columns)))
+		return
+	}
+
+	desc := strings.ToUpper(order) == "DESC"
+	if backward {
+		desc = !desc
+	}
+
+	direction, operator := "ASC", ">"
+	if desc {
+		direction, operator = "DESC", "<"
+	}
+
+	if len(values) > 0 {
+		placeholders := make([]string, len(values))
+		for i := range values {
+			placeholders[i] = fmt.Sprintf("$%d", b.nextArg())
+		}
+
+		condition := fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operator, strings.Join(placeholders, ", "))
+		b.where = append(b.where, condition)
+		b.args = append(b.args, values...)
+	}
+
+	b.order = []sortClause{}
+	for _, column := range columns {
+		b.order = append(b.order, sortClause{column: column, direction: direction})
+	}
+
+	if limit > 0 {
+		b.limit = fmt.Sprintf("LIMIT %d", limit+1)
+	}
+	b.offset = ""
+}
+
 // Build returns the final SQL query and args.
-func (b *SQLBuilder) Build() (string, []any) {
+// It returns the errors accumulated while adding clauses, e.g. an unknown operator.
+func (b *SQLBuilder) Build() (string, []any, error) {
+	if err := b.Err(); err != nil {
+		return "", nil, err
+	}
+
+	if b.isMutation() && len(b.where) == 0 && !b.fullTable && !hasTopLevelWhere(b.base.String()) {
+		return "", nil, ErrMissingWhere
+	}
+
+	sql, args := b.build(true)
+	return sql, args, nil
+}
+
+// Err returns the errors accumulated while adding clauses, if any.
+func (b *SQLBuilder) Err() error {
+	return errors.Join(b.errs...)
+}
+
+// SetCountExpr sets the aggregate used by Count, e.g. "COUNT(DISTINCT user_id)".
+// It is evaluated over the columns of the built query. Defaults to "COUNT(*)".
+func (b *SQLBuilder) SetCountExpr(expr string) {
+	b.countExpr = expr
+}
+
+// Count builds a SQL query for counting number of rows with filters (no order/limit/offset).
+// The built query is wrapped as a sub-query so that DISTINCT, GROUP BY, CTEs
+// and sub-queries in the base SQL are counted correctly.
+func (b *SQLBuilder) Count() (string, []any, error) {
+	if err := b.Err(); err != nil {
+		return "", nil, err
+	}
+
+	if b.from == "" && strings.TrimSpace(b.base.String()) == "" {
+		return "", nil, errors.New("dbx: count requires a base SQL")
+	}
+
+	if b.isMutation() {
+		return "", nil, errors.New("dbx: count requires a SELECT, not an UPDATE or DELETE")
+	}
+
+	countExpr := b.countExpr
+	if countExpr == "" {
+		countExpr = "COUNT(*)"
+	}
+
+	sql, args := b.build(false)
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(countExpr)
+	sb.WriteString(" FROM (")
+	sb.WriteString(sql)
+	sb.WriteString(") AS count_alias")
+
+	return sb.String(), args, nil
+}
+
+// build renders the query and its args, with ORDER BY/LIMIT/OFFSET only if paginate is true.
+// The args of the ORDER BY are only bound here, after the args of every other clause.
+func (b *SQLBuilder) build(paginate bool) (string, []any) {
 	var final strings.Builder
-	final.WriteString(b.base.String())
+	args := slices.Clip(b.args)
+
+	if len(b.ctes) > 0 {
+		final.WriteString("WITH ")
+		final.WriteString(strings.Join(b.ctes, ", "))
+		final.WriteString(" ")
+	}
+
+	b.writeBase(&final)
+
+	b.writeJoins(&final)
 
 	// If this is an UPDATE, add SET clause
 	if len(b.set) > 0 {
@@ -225,9 +617,20 @@ func (b *SQLBuilder) Build() (string, []any) {
 		final.WriteString(" GROUP BY ")
 		final.WriteString(strings.Join(b.groupBy, ", "))
 	}
-	if b.order != "" {
-		final.WriteString(" ")
-		final.WriteString(b.order)
+	if len(b.having) > 0 {
+		final.WriteString(" HAVING ")
+		final.WriteString(strings.Join(b.having, " AND "))
+	}
+
+	if !paginate {
+		return final.String(), args
+	}
+
+	var orderBy []string
+	orderBy, args = b.orderBy(args)
+	if len(orderBy) > 0 {
+		final.WriteString(" ORDER BY ")
+		final.WriteString(strings.Join(orderBy, ", "))
 	}
 	if b.limit != "" {
 		final.WriteString(" ")
@@ -237,48 +640,114 @@ func (b *SQLBuilder) Build() (string, []any) {
 		final.WriteString(" ")
 		final.WriteString(b.offset)
 	}
+	if len(b.returning) > 0 {
+		final.WriteString(" RETURNING ")
+		final.WriteString(strings.Join(b.returning, ", "))
+	}
 
-	return final.String(), b.args
+	return final.String(), args
 }
 
-// Count builds a SQL query for counting number of rows with filters (no order/limit/offset).
-func (b *SQLBuilder) Count() (string, []any) {
-	var sb strings.Builder
-	sb.WriteString("SELECT COUNT(*) FROM (")
+// ArgNum returns the next argument number (for manual filter building).
+func (b *SQLBuilder) ArgNum() int {
+	return b.argNum
+}
+
+// AppendWhere allows adding a custom WHERE clause with arguments.
+// The condition can reference args with placeholders that are rewritten into
+// the builder's "$N" numbers as they are added:
+//
+//	b.AppendWhere("status = ? OR owner_id = ?", status, ownerID)
+//	b.AppendWhere("status = @status OR reviewer_id = @user OR owner_id = @user", pgx.NamedArgs{"status": status, "user": userID})
+//	b.AppendWhere(fmt.Sprintf("status = $%d", b.ArgNum()), status)
+//
+// Write "??" for a literal "?" when using "?" placeholders.
+// The condition is parenthesized so that an "OR" inside it can not leak into the other conditions.
+func (b *SQLBuilder) AppendWhere(condition string, args ...any) {
+	condition, args, ok := b.bind(condition, args)
+	if !ok {
+		return
+	}
 
-	base := b.base.String()
+	b.where = append(b.where, "("+condition+")")
+	b.args = append(b.args, args...)
+	b.argNum += len(args)
+}
 
-	fromIndex := strings.Index(strings.ToUpper(base), "FROM")
-	if fromIndex == -1 {
-		panic("base SQL must include FROM clause")
+// orderBy returns the ORDER BY entries including the tiebreaker.
+// orderBy renders the ORDER BY clauses, binding their args after args.
+func (b *SQLBuilder) orderBy(args []any) ([]string, []any) {
+	orderBy := []string{}
+	hasTiebreaker := false
+
+	for _, clause := range b.order {
+		if len(clause.args) > 0 {
+			offset := len(args)
+			clause.column = rewritePlaceholders(clause.column, func(n int) string {
+				return "$" + strconv.Itoa(n+offset)
+			})
+			args = append(args, clause.args...)
+		}
+
+		orderBy = append(orderBy, clause.String())
+		if clause.column == b.tiebreaker {
+			hasTiebreaker = true
+		}
 	}
-	sb.WriteString("SELECT 1 ")
-	sb.WriteString(base[fromIndex:]) // FROM ... onwards
 
-	if len(b.where) > 0 {
-		sb.WriteString(" WHERE ")
-		sb.WriteString(strings.Join(b.where, " AND "))
+	if b.tiebreaker != "" && !hasTiebreaker {
+		orderBy = append(orderBy, sortClause{column: b.tiebreaker, direction: "ASC"}.String())
 	}
 
-	if len(b.groupBy) > 0 {
-		sb.WriteString(" GROUP BY ")
-		sb.WriteString(strings.Join(b.groupBy, ", "))
+	return orderBy, args
+}
+
+func (b *SQLBuilder) writeJoins(sb *strings.Builder) {
+	for _, join := range b.joins {
+		sb.WriteString(" ")
+		sb.WriteString(join)
+	}
+}
+
+// bind rewrites the placeholders of sql into "$N" numbers starting at ArgNum()
+// and checks that every arg is referenced. It records the error and returns false on failure.
+func (b *SQLBuilder) bind(sql string, args []any) (string, []any, bool) {
+	sql, args, err := bindPlaceholders(sql, b.argNum, args)
+	if err == nil {
+		err = checkArgs(sql, b.argNum, len(args))
+	}
+
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return "", nil, false
 	}
-	sb.WriteString(") AS count_alias")
 
-	return sb.String(), b.args
+	return sql, args, true
 }
 
-// ArgNum returns the next argument number (for manual filter building).
-func (b *SQLBuilder) ArgNum() int {
-	return b.argNum
+// addErr records err for the clause, e.g. "dbx: empty column: SET".
+func (b *SQLBuilder) addErr(err error, clause string) {
+	b.errs = append(b.errs, fmt.Errorf("%w: %s", err, clause))
 }
 
-// AppendWhere allows adding a custom WHERE clause with arguments.
-func (b *SQLBuilder) AppendWhere(condition string, args ...any) {
-	b.where = append(b.where, condition)
-	b.args = append(b.args, args...)
-	b.argNum += len(args)
+// checkArgs checks that sql references exactly the "$N" placeholders
+// from argNum to argNum+count-1. Placeholders before argNum may be reused.
+func checkArgs(sql string, argNum, count int) error {
+	referenced := map[int]bool{}
+	for _, n := range placeholderNumbers(sql) {
+		if n >= argNum+count {
+			return fmt.Errorf("%w: %q references $%d but only %d args were given", ErrArgCount, sql, n, count)
+		}
+		referenced[n] = true
+	}
+
+	for n := argNum; n < argNum+count; n++ {
+		if !referenced[n] {
+			return fmt.Errorf("%w: %q does not reference $%d", ErrArgCount, sql, n)
+		}
+	}
+
+	return nil
 }
 
 func (b *SQLBuilder) nextArg() int {
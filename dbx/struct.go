@@ -0,0 +1,77 @@
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// structColumns returns the column names and values of a struct using its `db` tags.
+// A `db:"-"` tag skips the field and untagged embedded structs are flattened like
+// pgx.RowToStructByName does. Unlike pgx, which matches untagged fields to columns
+// loosely, every other exported field must have a `db` tag, as its Go name (e.g. "CreatedAt")
+// is not a column name. Columns listed in omit are skipped.
+func structColumns(v any, omit ...string) ([]string, []any, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil, fmt.Errorf("dbx: expected a struct, got nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("dbx: expected a struct, got %s", rv.Type())
+	}
+
+	columns := []string{}
+	values := []any{}
+	if err := collectStructColumns(rv, omit, &columns, &values); err != nil {
+		return nil, nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("dbx: struct %s has no columns", rv.Type())
+	}
+
+	return columns, values, nil
+}
+
+func collectStructColumns(rv reflect.Value, omit []string, columns *[]string, values *[]any) error {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, hasTag := field.Tag.Lookup("db")
+		name, _, _ := strings.Cut(tag, ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+			if err := collectStructColumns(rv.Field(i), omit, columns, values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			return fmt.Errorf("dbx: field %s.%s has no db tag", rt, field.Name)
+		}
+
+		if slices.Contains(omit, name) {
+			continue
+		}
+
+		*columns = append(*columns, name)
+		*values = append(*values, rv.Field(i).Interface())
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package dbx
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// InSubquery is a condition like "column IN (sub-query)".
+func InSubquery(column string, sub *SQLBuilder) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		if column == "" {
+			b.addErr(ErrEmptyColumn, "IN sub-query")
+			return ""
+		}
+
+		sql, ok := b.embed(sub)
+		if !ok {
+			return ""
+		}
+
+		return fmt.Sprintf("%s IN (%s)", column, sql)
+	})
+}
+
+// Exists is a condition like "EXISTS (sub-query)".
+func Exists(sub *SQLBuilder) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		sql, ok := b.embed(sub)
+		if !ok {
+			return ""
+		}
+
+		return fmt.Sprintf("EXISTS (%s)", sql)
+	})
+}
+
+// AddInSubquery adds a condition like "column IN (sub-query)".
+func (b *SQLBuilder) AddInSubquery(column string, sub *SQLBuilder) {
+	b.AddCondition(InSubquery(column, sub))
+}
+
+// AddExists adds a condition like "EXISTS (sub-query)".
+func (b *SQLBuilder) AddExists(sub *SQLBuilder) {
+	b.AddCondition(Exists(sub))
+}
+
+// With adds a common table expression: "WITH name AS (sub-query) ...".
+func (b *SQLBuilder) With(name string, sub *SQLBuilder) {
+	if name == "" {
+		b.addErr(ErrEmptyColumn, "WITH")
+		return
+	}
+
+	sql, ok := b.embed(sub)
+	if !ok {
+		return
+	}
+
+	b.ctes = append(b.ctes, fmt.Sprintf("%s AS (%s)", name, sql))
+}
+
+// embed builds sub and renumbers its "$N" placeholders so that they follow
+// the builder's args, then appends sub's args.
+// The sub-query is built at this point, later changes to sub are not reflected.
+func (b *SQLBuilder) embed(sub *SQLBuilder) (string, bool) {
+	if sub == nil {
+		b.errs = append(b.errs, fmt.Errorf("dbx: nil sub-query"))
+		return "", false
+	}
+
+	sql, args, err := sub.Build()
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("sub-query: %w", err))
+		return "", false
+	}
+
+	offset := b.argNum - 1
+	sql = rewritePlaceholders(sql, func(n int) string {
+		return "$" + strconv.Itoa(n+offset)
+	})
+
+	b.args = append(b.args, args...)
+	b.argNum += len(args)
+
+	return sql, true
+}
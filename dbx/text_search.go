@@ -0,0 +1,122 @@
+package dbx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// textSearchConfig matches text search configuration names like "english" or "pg_catalog.simple".
+var textSearchConfig = regexp.MustCompile(`^[a-z_]+(\.[a-z_]+)?$`)
+
+// likeEscaper escapes the LIKE wildcards in user input using the default "\" escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// FullText is a condition like "to_tsvector('english', document) @@ websearch_to_tsquery('english', $N)".
+// The language is a text search configuration, e.g. "english" or "simple".
+// Without a language PostgreSQL's default_text_search_config is used.
+func FullText(document, query, language string) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		if query == "" {
+			return ""
+		}
+
+		vector, ok := b.tsVector(document, language)
+		if !ok {
+			return ""
+		}
+
+		return fmt.Sprintf("%s @@ %s", vector, b.tsQuery(query, language))
+	})
+}
+
+// Similar is a pg_trgm condition like "similarity(column, $N) >= $N+1", or "column % $N"
+// (using pg_trgm.similarity_threshold) when threshold is 0. It requires the pg_trgm extension.
+func Similar(column, value string, threshold float64) Condition {
+	return conditionFunc(func(b *SQLBuilder) string {
+		if value == "" {
+			return ""
+		}
+
+		if column == "" {
+			b.addErr(ErrEmptyColumn, "similarity filter")
+			return ""
+		}
+
+		b.args = append(b.args, value)
+		if threshold <= 0 {
+			return fmt.Sprintf("%s %% $%d", column, b.nextArg())
+		}
+
+		b.args = append(b.args, threshold)
+		return fmt.Sprintf("similarity(%s, $%d) >= $%d", column, b.nextArg(), b.nextArg())
+	})
+}
+
+// AddFullTextFilter adds a full-text condition, see FullText.
+func (b *SQLBuilder) AddFullTextFilter(document, query, language string) {
+	b.AddCondition(FullText(document, query, language))
+}
+
+// AddSimilarityFilter adds a trigram similarity condition, see Similar.
+func (b *SQLBuilder) AddSimilarityFilter(column, value string, threshold float64) {
+	b.AddCondition(Similar(column, value, threshold))
+}
+
+// AddRankSorting adds "ts_rank(to_tsvector(...), websearch_to_tsquery(..., $N))" to the ORDER BY clause.
+// Use order "desc" to show the most relevant rows first.
+func (b *SQLBuilder) AddRankSorting(document, query, language, order string) {
+	if query == "" {
+		return
+	}
+
+	vector, ok := b.tsVector(document, language)
+	if !ok {
+		return
+	}
+
+	b.addSortingExpr(fmt.Sprintf("ts_rank(%s, %s)", vector, tsQueryExpr("$1", language)), order, query)
+}
+
+// AddSimilaritySorting adds "similarity(column, $N)" to the ORDER BY clause.
+func (b *SQLBuilder) AddSimilaritySorting(column, value, order string) {
+	if column == "" || value == "" {
+		return
+	}
+
+	b.addSortingExpr(fmt.Sprintf("similarity(%s, $1)", column), order, value)
+}
+
+// tsVector renders "to_tsvector('language', document)" after validating the language.
+func (b *SQLBuilder) tsVector(document, language string) (string, bool) {
+	if document == "" {
+		b.addErr(ErrEmptyColumn, "full-text search")
+		return "", false
+	}
+
+	if language == "" {
+		return fmt.Sprintf("to_tsvector(%s)", document), true
+	}
+
+	if !textSearchConfig.MatchString(language) {
+		b.errs = append(b.errs, fmt.Errorf("dbx: invalid text search language: %q", language))
+		return "", false
+	}
+
+	return fmt.Sprintf("to_tsvector('%s', %s)", language, document), true
+}
+
+// tsQuery renders "websearch_to_tsquery('language', $N)" and adds the query arg.
+// The language must already be validated by tsVector.
+func (b *SQLBuilder) tsQuery(query, language string) string {
+	b.args = append(b.args, query)
+	return tsQueryExpr(fmt.Sprintf("$%d", b.nextArg()), language)
+}
+
+// tsQueryExpr renders "websearch_to_tsquery('language', placeholder)".
+func tsQueryExpr(placeholder, language string) string {
+	if language == "" {
+		return fmt.Sprintf("websearch_to_tsquery(%s)", placeholder)
+	}
+	return fmt.Sprintf("websearch_to_tsquery('%s', %s)", language, placeholder)
+}
@@ -0,0 +1,677 @@
+package dbx
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mudgallabs/tantra/logger"
+	"go.uber.org/zap"
+)
+
+// redacted replaces the logged value of redacted args.
+const redacted = "[REDACTED]"
+
+// otherStatements collects the stats of statements beyond TracerConfig.MaxStatements.
+const otherStatements = "other"
+
+// expressionEnd are the keywords that end the expression compared to or assigned to a column.
+var expressionEnd = map[string]bool{
+	"AND": true, "OR": true, "WHERE": true, "FROM": true, "RETURNING": true, "ON": true,
+	"GROUP": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "HAVING": true, "WINDOW": true,
+	"THEN": true, "WHEN": true, "ELSE": true, "END": true,
+}
+
+// LatencyBuckets are the upper bounds of the statement latency histogram buckets.
+// StatementStats.Buckets has one more bucket for latencies above the last bound.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+var (
+	// columnOperator matches a column compared to or assigned an expression, e.g. "password =".
+	columnOperator = regexp.MustCompile(`(?i)("?[a-z_][a-z0-9_."]*)\s*(?:<>|!=|<=|>=|=|<|>|\bLIKE\b|\bILIKE\b)`)
+
+	// insertColumns matches the column list and VALUES of an INSERT statement.
+	insertColumns = regexp.MustCompile(`(?is)INSERT\s+INTO\s+\S+\s*\(([^)]*)\)\s*VALUES\s*(.*)`)
+
+	// whitespace is collapsed when normalizing statements.
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+type TracerConfig struct {
+	// SlowQueryThreshold logs queries that take longer at warn level, with their duration and row count.
+	// Zero disables slow query logging.
+	SlowQueryThreshold time.Duration
+
+	// SlowAcquireThreshold logs waits for a pool connection that take longer at warn level,
+	// with the pool usage. Zero disables slow acquire logging.
+	SlowAcquireThreshold time.Duration
+
+	// RedactColumns are the columns whose args are never logged, e.g. "password", "token".
+	// Only the args bound to these columns are redacted in these statement shapes:
+	//
+	//	password = $1, password LIKE lower($1)     the whole expression after the column and operator
+	//	INSERT INTO users (password) VALUES ($1)    the values in the position of the column
+	//	pgx.NamedArgs{"password": ...}              the keys named after the column
+	//
+	// When a statement mentions one of these columns in any other way, e.g. "$1 = password",
+	// "SET (name, password) = ($1, $2)" or "SELECT id, password FROM", all of its args are redacted.
+	RedactColumns []string
+
+	// RedactPatterns redact string args matching any of the patterns, e.g. API keys.
+	RedactPatterns []*regexp.Regexp
+
+	// MaxStatements limits the number of distinct statements stats are kept for.
+	// Statements beyond the limit are counted under "other". Defaults to 1000.
+	MaxStatements int
+}
+
+// StatementStats are the latency histogram and error counter of a statement.
+type StatementStats struct {
+	// SQL is the statement with its whitespace collapsed.
+	SQL string
+
+	Count  int64
+	Errors int64
+	Total  time.Duration
+	Max    time.Duration
+
+	// Buckets counts the executions by latency, see LatencyBuckets.
+	Buckets []int64
+}
+
+// Tracer is the pgx tracer used by New. It logs SQL queries at debug level with
+// redacted args, logs slow queries at warn level and keeps per-statement stats.
+// Besides queries it traces batches, COPY, prepared statements, connection
+// establishment and pool acquire waits.
+type Tracer struct {
+	config        TracerConfig
+	redactColumns map[string]bool
+
+	mu    sync.Mutex
+	stats map[string]*StatementStats
+}
+
+// traceCtxKey stores the operation being traced between its start and end callbacks.
+// Each kind of operation has its own key so that nested operations do not overwrite each other.
+type traceCtxKey int
+
+const (
+	traceQueryKey traceCtxKey = iota
+	traceBatchKey
+	traceCopyFromKey
+	tracePrepareKey
+	traceConnectKey
+	traceAcquireKey
+)
+
+type traceSpan struct {
+	start time.Time
+	sql   string
+	args  []any
+
+	// last is when the previous batch query finished, used to time the next one.
+	last    time.Time
+	queries int
+}
+
+func startSpan(ctx context.Context, key traceCtxKey, span *traceSpan) context.Context {
+	span.start = time.Now()
+	span.last = span.start
+	return context.WithValue(ctx, key, span)
+}
+
+func spanFromCtx(ctx context.Context, key traceCtxKey) (*traceSpan, bool) {
+	span, ok := ctx.Value(key).(*traceSpan)
+	return span, ok
+}
+
+func NewTracer(config TracerConfig) *Tracer {
+	if config.MaxStatements <= 0 {
+		config.MaxStatements = 1000
+	}
+
+	columns := map[string]bool{}
+	for _, column := range config.RedactColumns {
+		columns[strings.ToLower(column)] = true
+	}
+
+	return &Tracer{
+		config:        config,
+		redactColumns: columns,
+		stats:         map[string]*StatementStats{},
+	}
+}
+
+func (t *Tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	l := logger.FromCtx(ctx)
+	if l.Desugar().Core().Enabled(zap.DebugLevel) {
+		l.Debugw("executing SQL query", "sqlstr", data.SQL, "args", t.RedactArgs(data.SQL, data.Args))
+	}
+
+	return startSpan(ctx, traceQueryKey, &traceSpan{sql: data.SQL, args: data.Args})
+}
+
+func (t *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := spanFromCtx(ctx, traceQueryKey)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(query.start)
+	t.record(query.sql, duration, data.Err)
+
+	l := logger.FromCtx(ctx)
+	if data.Err != nil {
+		l.Debugw("error executing SQL query", "err", data.Err, "duration", duration)
+	}
+
+	if t.isSlow(duration) {
+		l.Warnw("slow SQL query",
+			"sqlstr", query.sql,
+			"args", t.RedactArgs(query.sql, query.args),
+			"duration", duration,
+			"rows", data.CommandTag.RowsAffected(),
+		)
+	}
+}
+
+func (t *Tracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	l := logger.FromCtx(ctx)
+	l.Debugw("sending SQL batch", "queries", data.Batch.Len())
+
+	return startSpan(ctx, traceBatchKey, &traceSpan{})
+}
+
+func (t *Tracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	batch, ok := spanFromCtx(ctx, traceBatchKey)
+	if !ok {
+		return
+	}
+
+	// Batch queries are sent together, so a query's duration is the time since the previous one finished.
+	now := time.Now()
+	duration := now.Sub(batch.last)
+	batch.last = now
+	batch.queries++
+
+	t.record(data.SQL, duration, data.Err)
+
+	l := logger.FromCtx(ctx)
+	if l.Desugar().Core().Enabled(zap.DebugLevel) {
+		l.Debugw("executed batch SQL query",
+			"sqlstr", data.SQL,
+			"args", t.RedactArgs(data.SQL, data.Args),
+			"duration", duration,
+			"rows", data.CommandTag.RowsAffected(),
+			"err", data.Err,
+		)
+	}
+}
+
+func (t *Tracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	batch, ok := spanFromCtx(ctx, traceBatchKey)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(batch.start)
+
+	l := logger.FromCtx(ctx)
+	if data.Err != nil {
+		l.Debugw("error executing SQL batch", "err", data.Err, "queries", batch.queries, "duration", duration)
+	}
+
+	if t.isSlow(duration) {
+		l.Warnw("slow SQL batch", "queries", batch.queries, "duration", duration)
+	}
+}
+
+func (t *Tracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	sql := fmt.Sprintf("COPY %s (%s) FROM STDIN", data.TableName.Sanitize(), strings.Join(data.ColumnNames, ", "))
+
+	l := logger.FromCtx(ctx)
+	l.Debugw("copying rows", "sqlstr", sql)
+
+	return startSpan(ctx, traceCopyFromKey, &traceSpan{sql: sql})
+}
+
+func (t *Tracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	copyFrom, ok := spanFromCtx(ctx, traceCopyFromKey)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(copyFrom.start)
+	t.record(copyFrom.sql, duration, data.Err)
+
+	l := logger.FromCtx(ctx)
+	if data.Err != nil {
+		l.Debugw("error copying rows", "sqlstr", copyFrom.sql, "err", data.Err, "duration", duration)
+		return
+	}
+
+	rows := data.CommandTag.RowsAffected()
+	if t.isSlow(duration) {
+		l.Warnw("slow COPY", "sqlstr", copyFrom.sql, "rows", rows, "duration", duration)
+		return
+	}
+
+	l.Debugw("copied rows", "sqlstr", copyFrom.sql, "rows", rows, "duration", duration)
+}
+
+func (t *Tracer) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	return startSpan(ctx, tracePrepareKey, &traceSpan{sql: data.SQL})
+}
+
+func (t *Tracer) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData) {
+	prepare, ok := spanFromCtx(ctx, tracePrepareKey)
+	if !ok || data.AlreadyPrepared {
+		return
+	}
+
+	duration := time.Since(prepare.start)
+
+	l := logger.FromCtx(ctx)
+	if data.Err != nil {
+		l.Debugw("error preparing SQL statement", "sqlstr", prepare.sql, "err", data.Err, "duration", duration)
+		return
+	}
+
+	l.Debugw("prepared SQL statement", "sqlstr", prepare.sql, "duration", duration)
+}
+
+func (t *Tracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	span := &traceSpan{}
+	if data.ConnConfig != nil {
+		span.sql = fmt.Sprintf("%s:%d/%s", data.ConnConfig.Host, data.ConnConfig.Port, data.ConnConfig.Database)
+	}
+
+	return startSpan(ctx, traceConnectKey, span)
+}
+
+func (t *Tracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	connect, ok := spanFromCtx(ctx, traceConnectKey)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(connect.start)
+
+	l := logger.FromCtx(ctx)
+	if data.Err != nil {
+		l.Errorw("error connecting to database", "database", connect.sql, "err", data.Err, "duration", duration)
+		return
+	}
+
+	l.Debugw("established database connection", "database", connect.sql, "duration", duration)
+}
+
+func (t *Tracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireStartData) context.Context {
+	return startSpan(ctx, traceAcquireKey, &traceSpan{})
+}
+
+func (t *Tracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	acquire, ok := spanFromCtx(ctx, traceAcquireKey)
+	if !ok {
+		return
+	}
+
+	wait := time.Since(acquire.start)
+
+	l := logger.FromCtx(ctx)
+	if data.Err != nil {
+		l.Debugw("error acquiring database connection", "err", data.Err, "wait", wait)
+		return
+	}
+
+	if t.config.SlowAcquireThreshold > 0 && wait >= t.config.SlowAcquireThreshold {
+		stat := pool.Stat()
+		l.Warnw("slow database connection acquire",
+			"wait", wait,
+			"total_conns", stat.TotalConns(),
+			"acquired_conns", stat.AcquiredConns(),
+			"max_conns", stat.MaxConns(),
+		)
+	}
+}
+
+// RedactArgs returns a copy of args safe for logging: args bound to the configured
+// columns and string args matching the configured patterns are replaced with "[REDACTED]".
+// pgx.NamedArgs and pgx.StrictNamedArgs are redacted by their keys, as pgx traces
+// the query before rewriting its "@name" placeholders.
+func (t *Tracer) RedactArgs(sql string, args []any) []any {
+	if len(args) == 0 || (len(t.redactColumns) == 0 && len(t.config.RedactPatterns) == 0) {
+		return args
+	}
+
+	placeholders, all := t.redactedPlaceholders(sql)
+
+	if len(args) == 1 {
+		switch named := args[0].(type) {
+		case pgx.NamedArgs:
+			return []any{pgx.NamedArgs(t.redactNamedArgs(placeholders, all, named))}
+		case pgx.StrictNamedArgs:
+			return []any{pgx.StrictNamedArgs(t.redactNamedArgs(placeholders, all, named))}
+		}
+	}
+
+	safe := make([]any, len(args))
+	for i, arg := range args {
+		if all || placeholders["$"+strconv.Itoa(i+1)] || t.matchesPattern(arg) {
+			safe[i] = redacted
+			continue
+		}
+		safe[i] = arg
+	}
+
+	return safe
+}
+
+// redactNamedArgs returns a copy of args with the values of redacted placeholders,
+// keys named after redacted columns and values matching the patterns replaced.
+func (t *Tracer) redactNamedArgs(placeholders map[string]bool, all bool, args map[string]any) map[string]any {
+	safe := make(map[string]any, len(args))
+	for name, arg := range args {
+		if all || placeholders["@"+name] || t.isRedactedColumn(name) || t.matchesPattern(arg) {
+			safe[name] = redacted
+			continue
+		}
+		safe[name] = arg
+	}
+	return safe
+}
+
+// Stats returns a snapshot of the per-statement stats, slowest total time first.
+func (t *Tracer) Stats() []StatementStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := make([]StatementStats, 0, len(t.stats))
+	for _, s := range t.stats {
+		snapshot := *s
+		snapshot.Buckets = slices.Clone(s.Buckets)
+		stats = append(stats, snapshot)
+	}
+
+	slices.SortFunc(stats, func(a, b StatementStats) int {
+		return cmp.Or(cmp.Compare(b.Total, a.Total), strings.Compare(a.SQL, b.SQL))
+	})
+
+	return stats
+}
+
+// ResetStats clears the per-statement stats.
+func (t *Tracer) ResetStats() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats = map[string]*StatementStats{}
+}
+
+func (t *Tracer) isSlow(duration time.Duration) bool {
+	return t.config.SlowQueryThreshold > 0 && duration >= t.config.SlowQueryThreshold
+}
+
+func (t *Tracer) record(sql string, duration time.Duration, err error) {
+	key := normalizeSQL(sql)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.stats[key]
+	if !ok {
+		if len(t.stats) >= t.config.MaxStatements {
+			key = otherStatements
+			s = t.stats[key]
+		}
+		if s == nil {
+			s = &StatementStats{SQL: key, Buckets: make([]int64, len(LatencyBuckets)+1)}
+			t.stats[key] = s
+		}
+	}
+
+	s.Count++
+	s.Total += duration
+	s.Max = max(s.Max, duration)
+	if err != nil {
+		s.Errors++
+	}
+
+	bucket, _ := slices.BinarySearch(LatencyBuckets, duration)
+	s.Buckets[bucket]++
+}
+
+// redactedPlaceholders returns the placeholders ("$N" or "@name") bound to the redacted columns in sql,
+// in the shapes described by TracerConfig.RedactColumns. It returns true instead when a redacted
+// column is mentioned anywhere else, e.g. "$1 = password", so that every arg is redacted.
+func (t *Tracer) redactedPlaceholders(sql string) (map[string]bool, bool) {
+	placeholders := map[string]bool{}
+	if len(t.redactColumns) == 0 {
+		return placeholders, false
+	}
+
+	// handled are the [start, end) ranges of sql whose mentions of redacted columns are understood.
+	handled := [][2]int{}
+
+	for _, match := range columnOperator.FindAllStringSubmatchIndex(sql, -1) {
+		if !t.isRedactedColumn(sql[match[2]:match[3]]) {
+			continue
+		}
+
+		end := scanPlaceholders(sql[match[1]:], func(token string, item int) {
+			placeholders[token] = true
+		}, true)
+		handled = append(handled, [2]int{match[0], match[1] + end})
+	}
+
+	// INSERT INTO table (a, b) VALUES ($1, $2), ($3, lower($4)): each value maps to a column by position.
+	if match := insertColumns.FindStringSubmatchIndex(sql); match != nil {
+		columns := strings.Split(sql[match[2]:match[3]], ",")
+		scanPlaceholders(valuesList(sql[match[4]:match[5]]), func(token string, item int) {
+			if item < len(columns) && t.isRedactedColumn(columns[item]) {
+				placeholders[token] = true
+			}
+		}, false)
+		handled = append(handled, [2]int{match[2], match[3]})
+	}
+
+	all := false
+	scanIdentifiers(sql, func(start int, identifier string) bool {
+		if !t.isRedactedColumn(identifier) {
+			return true
+		}
+
+		all = !slices.ContainsFunc(handled, func(r [2]int) bool { return start >= r[0] && start < r[1] })
+		return !all
+	})
+
+	return placeholders, all
+}
+
+// scanIdentifiers calls fn with the start and the name of every identifier in sql,
+// outside string literals and including quoted identifiers, until fn returns false.
+func scanIdentifiers(sql string, fn func(start int, identifier string) bool) {
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		switch {
+		case c == '\'':
+			end := strings.IndexByte(sql[i+1:], '\'')
+			if end < 0 {
+				return
+			}
+			i += end + 1
+
+		case c == '"':
+			end := strings.IndexByte(sql[i+1:], '"')
+			if end < 0 {
+				return
+			}
+			if !fn(i, sql[i+1:i+1+end]) {
+				return
+			}
+			i += end + 1
+
+		case c == '$' || c == '@':
+			// Skip placeholders, "$1" and "@password" are not columns.
+			for i+1 < len(sql) && (isIdentStart(sql[i+1]) || isDigit(sql[i+1])) {
+				i++
+			}
+
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(sql) && (isIdentStart(sql[j]) || isDigit(sql[j])) {
+				j++
+			}
+			if !fn(i, sql[i:j]) {
+				return
+			}
+			i = j - 1
+		}
+	}
+}
+
+// scanPlaceholders calls fn for every "$N" or "@name" placeholder in sql outside quotes,
+// with the index of the comma separated item within the outermost parentheses,
+// e.g. the value index of a VALUES row.
+//
+// With expr set, sql is the right-hand side of a comparison or assignment and the scan stops
+// at the end of that expression: a comma, a closing parenthesis or a keyword at depth 0.
+// It returns where the scan stopped.
+func scanPlaceholders(sql string, fn func(token string, item int), expr bool) int {
+	var quote byte
+	depth, item := 0, 0
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+
+		case c == '\'' || c == '"':
+			quote = c
+
+		case c == '(':
+			depth++
+			if depth == 1 {
+				item = 0
+			}
+
+		case c == ')':
+			depth--
+			if depth < 0 && expr {
+				return i
+			}
+
+		case c == ',':
+			if depth == 0 && expr {
+				return i
+			}
+			if depth == 1 {
+				item++
+			}
+
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			j := i + 1
+			for j < len(sql) && isDigit(sql[j]) {
+				j++
+			}
+			fn(sql[i:j], item)
+			i = j - 1
+
+		case c == '@' && i+1 < len(sql) && isIdentStart(sql[i+1]):
+			j := i + 1
+			for j < len(sql) && (isIdentStart(sql[j]) || isDigit(sql[j])) {
+				j++
+			}
+			fn(sql[i:j], item)
+			i = j - 1
+
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(sql) && (isIdentStart(sql[j]) || isDigit(sql[j])) {
+				j++
+			}
+			if expr && depth == 0 && expressionEnd[strings.ToUpper(sql[i:j])] {
+				return i
+			}
+			i = j - 1
+		}
+	}
+
+	return len(sql)
+}
+
+func (t *Tracer) isRedactedColumn(column string) bool {
+	column = strings.ToLower(strings.TrimSpace(column))
+	if i := strings.LastIndexByte(column, '.'); i >= 0 {
+		column = column[i+1:]
+	}
+	return t.redactColumns[strings.Trim(column, `"`)]
+}
+
+func (t *Tracer) matchesPattern(arg any) bool {
+	s, ok := arg.(string)
+	if !ok {
+		return false
+	}
+
+	for _, pattern := range t.config.RedactPatterns {
+		if pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuesList returns the VALUES rows of an INSERT statement, without the clauses that follow them.
+func valuesList(values string) string {
+	upper := strings.ToUpper(values)
+	end := len(values)
+	for _, clause := range []string{" ON CONFLICT", " RETURNING"} {
+		if i := strings.Index(upper, clause); i >= 0 && i < end {
+			end = i
+		}
+	}
+	return values[:end]
+}
+
+// normalizeSQL collapses whitespace so that the same statement built on
+// different lines or indentations is counted once.
+func normalizeSQL(sql string) string {
+	return strings.TrimSpace(whitespace.ReplaceAllString(sql, " "))
+}
+
+// Make sure that Tracer traces every operation pgx and pgxpool support.
+var (
+	_ pgx.QueryTracer       = (*Tracer)(nil)
+	_ pgx.BatchTracer       = (*Tracer)(nil)
+	_ pgx.CopyFromTracer    = (*Tracer)(nil)
+	_ pgx.PrepareTracer     = (*Tracer)(nil)
+	_ pgx.ConnectTracer     = (*Tracer)(nil)
+	_ pgxpool.AcquireTracer = (*Tracer)(nil)
+)
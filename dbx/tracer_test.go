@@ -0,0 +1,110 @@
+package dbx
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestTracerRedactArgs(t *testing.T) {
+	tracer := NewTracer(TracerConfig{
+		RedactColumns:  []string{"password", "token"},
+		RedactPatterns: []*regexp.Regexp{regexp.MustCompile(`^sk_`)},
+	})
+
+	tests := []struct {
+		name string
+		sql  string
+		args []any
+		want []any
+	}{
+		{
+			name: "comparison",
+			sql:  "SELECT id FROM users WHERE u.password = $1 AND email = $2",
+			args: []any{"hunter2", "a@b.c"},
+			want: []any{redacted, "a@b.c"},
+		},
+		{
+			name: "expression after the column",
+			sql:  "UPDATE users SET password = crypt($2, password), name = $3 WHERE id = $1",
+			args: []any{1, "hunter2", "name"},
+			want: []any{1, redacted, "name"},
+		},
+		{
+			name: "expression ends at AND",
+			sql:  "SELECT id FROM users WHERE token = lower($1) AND id = $2",
+			args: []any{"secret", 2},
+			want: []any{redacted, 2},
+		},
+		{
+			name: "insert values by position",
+			sql:  "INSERT INTO users (email, password, name) VALUES ($1, crypt($2, gen_salt('bf')), $3), ($4, $5, $6) RETURNING id",
+			args: []any{1, 2, 3, 4, 5, 6},
+			want: []any{1, redacted, 3, 4, redacted, 6},
+		},
+		{
+			name: "quoted column",
+			sql:  `UPDATE users SET "password" = $1 WHERE id = $2`,
+			args: []any{"hunter2", 2},
+			want: []any{redacted, 2},
+		},
+		{
+			name: "column on the right falls back to all args",
+			sql:  "SELECT id FROM users WHERE $1 = password AND id = $2",
+			args: []any{"hunter2", 2},
+			want: []any{redacted, redacted},
+		},
+		{
+			name: "insert select falls back to all args",
+			sql:  "INSERT INTO users (name, password) SELECT $1, $2",
+			args: []any{"name", "hunter2"},
+			want: []any{redacted, redacted},
+		},
+		{
+			name: "row assignment falls back to all args",
+			sql:  "UPDATE users SET (name, password) = ($1, $2) WHERE id = $3",
+			args: []any{"name", "hunter2", 3},
+			want: []any{redacted, redacted, redacted},
+		},
+		{
+			name: "column in a string literal is not a mention",
+			sql:  "SELECT id FROM audit WHERE action = 'password' AND id = $1",
+			args: []any{1},
+			want: []any{1},
+		},
+		{
+			name: "pattern",
+			sql:  "SELECT id FROM keys WHERE key = $1 AND id = $2",
+			args: []any{"sk_live", 2},
+			want: []any{redacted, 2},
+		},
+		{
+			name: "named args by key",
+			sql:  "UPDATE users SET password = @password, name = @name WHERE id = @id",
+			args: []any{pgx.NamedArgs{"password": "hunter2", "name": "name", "id": 1}},
+			want: []any{pgx.NamedArgs{"password": redacted, "name": "name", "id": 1}},
+		},
+		{
+			name: "strict named args by placeholder",
+			sql:  "UPDATE users SET password = crypt(@new, password) WHERE id = @id",
+			args: []any{pgx.StrictNamedArgs{"new": "hunter2", "id": 1}},
+			want: []any{pgx.StrictNamedArgs{"new": redacted, "id": 1}},
+		},
+		{
+			name: "named args fall back to all args",
+			sql:  "UPDATE users SET (name, password) = (@name, @new) WHERE id = @id",
+			args: []any{pgx.NamedArgs{"new": "hunter2", "name": "name", "id": 1}},
+			want: []any{pgx.NamedArgs{"new": redacted, "name": redacted, "id": redacted}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tracer.RedactArgs(tt.sql, tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RedactArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
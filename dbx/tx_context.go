@@ -0,0 +1,161 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mudgallabs/tantra/logger"
+)
+
+// ErrNoTx is returned when registering an after commit hook outside of a transaction.
+var ErrNoTx = errors.New("dbx: no transaction")
+
+type txCtxKey struct{}
+
+// txStates maps the transactions begun by WithTx and InTx to their state while they run,
+// so that hooks can be registered with the pgx.Tx passed to WithTx, see AfterCommitTx.
+var txStates sync.Map
+
+// txState is the transaction stored in the context by WithTx and InTx.
+type txState struct {
+	tx pgx.Tx
+
+	// parent is the enclosing transaction when tx is a SAVEPOINT.
+	parent *txState
+
+	// detached is set for a SAVEPOINT on a pgx.Tx not begun by WithTx or InTx,
+	// whose commit is unknown, so that hooks can not be registered within it.
+	detached bool
+
+	// hooks run after the outermost transaction commits, see AfterCommit.
+	hooks []func(ctx context.Context) error
+}
+
+// contextWithTx returns a copy of ctx with the transaction state attached.
+func contextWithTx(ctx context.Context, state *txState) context.Context {
+	return context.WithValue(ctx, txCtxKey{}, state)
+}
+
+func txStateFromCtx(ctx context.Context) (*txState, bool) {
+	state, ok := ctx.Value(txCtxKey{}).(*txState)
+	return state, ok
+}
+
+// TxFromCtx returns the transaction of the InTx or WithTx call ctx belongs to, if any.
+func TxFromCtx(ctx context.Context) (pgx.Tx, bool) {
+	if state, ok := txStateFromCtx(ctx); ok {
+		return state.tx, true
+	}
+	return nil, false
+}
+
+// AfterCommit registers fn to run after the transaction ctx belongs to commits,
+// e.g. to enqueue emails, invalidate caches or publish events:
+//
+//	err := dbx.InTx(ctx, pool, func(ctx context.Context) error {
+//		...
+//		return dbx.AfterCommit(ctx, func(ctx context.Context) error {
+//			return mailer.SendWelcome(ctx, user)
+//		})
+//	})
+//
+// Within WithTx, whose fn gets the pgx.Tx and not the ctx, use AfterCommitTx.
+//
+// Hooks registered within a SAVEPOINT run when the outermost transaction commits.
+// Hooks are dropped if the transaction or SAVEPOINT rolls back or panics.
+// Errors returned by hooks are logged and do not affect the committed transaction.
+// Without a transaction in ctx, or within a SAVEPOINT on a pgx.Tx that was not begun
+// by WithTx or InTx, fn is not registered and ErrNoTx is returned.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	state, ok := txStateFromCtx(ctx)
+	if !ok {
+		return ErrNoTx
+	}
+
+	return state.afterCommit(fn)
+}
+
+// AfterCommitTx is like AfterCommit for the pgx.Tx passed to the fn of WithTx:
+//
+//	err := dbx.WithTx(ctx, pool, func(tx pgx.Tx) error {
+//		...
+//		return dbx.AfterCommitTx(tx, func(ctx context.Context) error {
+//			return cache.Invalidate(ctx, key)
+//		})
+//	})
+//
+// It returns ErrNoTx if tx was not begun by WithTx or InTx, or has already finished.
+func AfterCommitTx(tx pgx.Tx, fn func(ctx context.Context) error) error {
+	value, ok := txStates.Load(tx)
+	if !ok {
+		return ErrNoTx
+	}
+
+	return value.(*txState).afterCommit(fn)
+}
+
+func (s *txState) afterCommit(fn func(ctx context.Context) error) error {
+	if s.detached {
+		return ErrNoTx
+	}
+
+	s.hooks = append(s.hooks, fn)
+	return nil
+}
+
+// runHooks runs the after commit hooks in order, logging errors and panics.
+func runHooks(ctx context.Context, hooks []func(ctx context.Context) error) {
+	l := logger.FromCtx(ctx)
+
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					l.Errorw("after commit hook panicked", "panic", p)
+				}
+			}()
+
+			if err := hook(ctx); err != nil {
+				l.Errorw("after commit hook failed", "error", err)
+			}
+		}()
+	}
+}
+
+// Executor returns the transaction in ctx if there is one, or db otherwise.
+// Repositories use it so that their queries join the unit of work started by the service:
+//
+//	func (r *UserRepository) Create(ctx context.Context, user *User) error {
+//		_, err := dbx.Executor(ctx, r.db).Exec(ctx, sql, args...)
+//		return err
+//	}
+func Executor(ctx context.Context, db DBExecutor) DBExecutor {
+	if tx, ok := TxFromCtx(ctx); ok {
+		return tx
+	}
+	return db
+}
+
+// InTx runs fn within a transaction carried by the ctx passed to fn,
+// so that repositories using Executor take part in it without passing a pgx.Tx around:
+//
+//	err := dbx.InTx(ctx, pool, func(ctx context.Context) error {
+//		if err := users.Create(ctx, user); err != nil {
+//			return err
+//		}
+//		return accounts.Create(ctx, account)
+//	})
+//
+// It commits, rolls back and nests like WithTx.
+func InTx(ctx context.Context, db TxBeginner, fn func(ctx context.Context) error) error {
+	return InTxOptions(ctx, db, TxOptions{}, fn)
+}
+
+// InTxOptions is like InTx but with the options of WithTxOptions.
+func InTxOptions(ctx context.Context, db TxBeginner, opts TxOptions, fn func(ctx context.Context) error) error {
+	return withTx(ctx, db, opts, func(ctx context.Context, tx pgx.Tx) error {
+		return fn(ctx)
+	})
+}
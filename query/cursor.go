@@ -1,10 +1,24 @@
 package query
 
 import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
 	"github.com/mudgallabs/tantra/apires"
+	"github.com/mudgallabs/tantra/cipher"
+	"github.com/mudgallabs/tantra/dbx"
 	"github.com/mudgallabs/tantra/service"
 )
 
+// ErrInvalidCursor is returned when a cursor token is malformed or was tampered with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type Cursor struct {
 	After  *string `query:"after" schema:"after" json:"after"`
 	Before *string `query:"before" schema:"before" json:"before"`
@@ -40,3 +54,164 @@ func (cursor *Cursor) AfterIsValid() bool {
 func (cursor *Cursor) BeforeIsValid() bool {
 	return cursor.Before != nil && *cursor.Before != ""
 }
+
+// Keyset is the sort key tuple used for cursor pagination, e.g. (created_at, id).
+// The last column must be unique so that the tuple identifies a single row.
+type Keyset struct {
+	Columns []string
+	Order   SortOrder
+}
+
+// Apply decodes the cursor token into dst (one pointer per keyset column)
+// and adds keyset pagination to the builder.
+// Call Validate before Apply so that the limit is set.
+func (cursor *Cursor) Apply(b *dbx.SQLBuilder, secret []byte, keyset Keyset, dst ...any) error {
+	token, backward := cursor.token()
+
+	values := []any{}
+	if token != "" {
+		if err := DecodeCursor(token, secret, dst...); err != nil {
+			return err
+		}
+
+		for _, d := range dst {
+			values = append(values, reflect.ValueOf(d).Elem().Interface())
+		}
+	}
+
+	limit := 0
+	if cursor.Limit != nil {
+		limit = *cursor.Limit
+	}
+
+	b.AddKeysetPagination(keyset.Columns, keyset.Order, values, backward, limit)
+	return nil
+}
+
+// token returns the token to page from and whether paging is backward.
+func (cursor *Cursor) token() (string, bool) {
+	if cursor.BeforeIsValid() {
+		return *cursor.Before, true
+	}
+	if cursor.AfterIsValid() {
+		return *cursor.After, false
+	}
+	return "", false
+}
+
+// EncodeCursor encodes the sort key values of a row into an opaque token
+// signed with secret so that clients cannot tamper with it.
+func EncodeCursor(secret []byte, values ...any) (string, error) {
+	payload, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+
+	data := base64.RawURLEncoding.EncodeToString(payload)
+	return data + "." + cipher.HashToken(data, secret), nil
+}
+
+// DecodeCursor verifies the token signature and decodes its values into dst, in order.
+func DecodeCursor(token string, secret []byte, dst ...any) error {
+	data, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(cipher.HashToken(data, secret))) {
+		return ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(payload, &values); err != nil {
+		return ErrInvalidCursor
+	}
+
+	if len(values) != len(dst) {
+		return ErrInvalidCursor
+	}
+
+	for i, value := range values {
+		if err := json.Unmarshal(value, dst[i]); err != nil {
+			return ErrInvalidCursor
+		}
+	}
+
+	return nil
+}
+
+type CursorMeta struct {
+	Next     *string `json:"next"`
+	Previous *string `json:"previous"`
+	HasMore  bool    `json:"has_more"`
+	Limit    int     `json:"limit"`
+}
+
+// CursorResult is the output of a cursor paginated search performed on a <resource>.
+// It takes a generic to define resource item type.
+type CursorResult[T any] struct {
+	Items  T          `json:"items"`
+	Cursor CursorMeta `json:"cursor"`
+}
+
+// NewCursorResult builds the result from rows fetched with Cursor.Apply,
+// which fetches one extra row to detect if there are more.
+// key returns the keyset values of an item in the order of the keyset columns.
+func NewCursorResult[T any](items []T, cursor Cursor, secret []byte, key func(T) []any) (*CursorResult[[]T], error) {
+	limit := len(items)
+	if cursor.Limit != nil {
+		limit = *cursor.Limit
+	}
+
+	_, backward := cursor.token()
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	// Rows were fetched in reverse order when paging backward.
+	if backward {
+		slices.Reverse(items)
+	}
+
+	meta := CursorMeta{
+		HasMore: hasMore,
+		Limit:   limit,
+	}
+
+	if len(items) == 0 {
+		return &CursorResult[[]T]{Items: items, Cursor: meta}, nil
+	}
+
+	first, err := EncodeCursor(secret, key(items[0])...)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := EncodeCursor(secret, key(items[len(items)-1])...)
+	if err != nil {
+		return nil, err
+	}
+
+	if backward {
+		meta.Next = &last
+		if hasMore {
+			meta.Previous = &first
+		}
+	} else {
+		if hasMore {
+			meta.Next = &last
+		}
+		if cursor.AfterIsValid() {
+			meta.Previous = &first
+		}
+	}
+
+	return &CursorResult[[]T]{Items: items, Cursor: meta}, nil
+}
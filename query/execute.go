@@ -0,0 +1,91 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mudgallabs/tantra/dbx"
+)
+
+type SearchOptions struct {
+	// Fields maps sort field names to SQL expressions.
+	// Without it the validated sort field names are used as columns.
+	Fields *FieldRegistry
+
+	// Concurrent runs the count and data queries at the same time.
+	// The executor must support concurrent queries, e.g. *pgxpool.Pool and not pgx.Tx.
+	Concurrent bool
+}
+
+// Search runs the search described by payload on top of the base builder:
+// it applies the filters and sorting, counts the matching rows, fetches the
+// requested page and scans every row with scan (e.g. pgx.RowToStructByName[Item]).
+// The payload must already be validated with Init or InitFields.
+// b itself is not changed, so that the same base builder can be reused for every search.
+func Search[F any, Item any](ctx context.Context, db dbx.DBExecutor, b *dbx.SQLBuilder, payload *SearchPayload[F], scan pgx.RowToFunc[Item], opts SearchOptions) (*SearchResult[[]Item], error) {
+	b = b.Clone()
+
+	if err := payload.ApplyFilters(b); err != nil {
+		return nil, err
+	}
+
+	if opts.Fields != nil {
+		if err := opts.Fields.ApplySorting(b, payload.Sort); err != nil {
+			return nil, err
+		}
+	} else {
+		payload.Sort.Apply(b)
+	}
+
+	countSQL, countArgs, err := b.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	b.AddPagination(payload.Pagination.Limit, payload.Pagination.Offset())
+	sql, args, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	var items []Item
+	var countErr, itemsErr error
+
+	count := func() {
+		countErr = db.QueryRow(ctx, countSQL, countArgs...).Scan(&total)
+	}
+
+	fetch := func() {
+		rows, err := db.Query(ctx, sql, args...)
+		if err != nil {
+			itemsErr = err
+			return
+		}
+		items, itemsErr = pgx.CollectRows(rows, scan)
+	}
+
+	if opts.Concurrent {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); count() }()
+		go func() { defer wg.Done(); fetch() }()
+		wg.Wait()
+	} else {
+		count()
+		if countErr == nil {
+			fetch()
+		}
+	}
+
+	if countErr != nil {
+		return nil, fmt.Errorf("count: %w", countErr)
+	}
+	if itemsErr != nil {
+		return nil, fmt.Errorf("search: %w", itemsErr)
+	}
+
+	return NewSearchResult(items, payload.Pagination.GetMeta(total)), nil
+}
@@ -0,0 +1,175 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mudgallabs/tantra/dbx"
+)
+
+// FieldType is the type of value a field can be filtered with.
+type FieldType string
+
+const (
+	FieldTypeAny    FieldType = ""
+	FieldTypeString FieldType = "string"
+	FieldTypeNumber FieldType = "number"
+	FieldTypeBool   FieldType = "bool"
+	FieldTypeTime   FieldType = "time"
+)
+
+// Field maps a public API field name to the SQL expression it is backed by.
+type Field struct {
+	// Name is the field name used by API clients, e.g. "created_at".
+	Name SearchField
+
+	// Expr is the trusted SQL expression, e.g. "u.created_at".
+	// It is never taken from client input.
+	Expr string
+
+	// Type is the type of value the field can be filtered with.
+	Type FieldType
+
+	// Sortable allows clients to sort on the field.
+	Sortable bool
+
+	// Operators allowed when filtering on the field.
+	// No operators means the field can not be filtered on.
+	Operators []dbx.Operator
+}
+
+// AllowsOperator reports whether the field can be filtered with the operator.
+func (f Field) AllowsOperator(op dbx.Operator) bool {
+	return slices.Contains(f.Operators, op)
+}
+
+// CheckValue reports an error if the value does not match the field type.
+// Slices are checked element by element, nil values are always valid.
+func (f Field) CheckValue(value any) error {
+	if value == nil || f.Type == FieldTypeAny {
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := 0; i < rv.Len(); i++ {
+			if err := f.CheckValue(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	valid := false
+	switch f.Type {
+	case FieldTypeString:
+		valid = rv.Kind() == reflect.String
+	case FieldTypeNumber:
+		valid = rv.CanInt() || rv.CanUint() || rv.CanFloat()
+	case FieldTypeBool:
+		valid = rv.Kind() == reflect.Bool
+	case FieldTypeTime:
+		_, valid = rv.Interface().(time.Time)
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid value for field %s: expected %s", f.Name, f.Type)
+	}
+
+	return nil
+}
+
+// FieldRegistry is the set of fields a <resource> can be sorted and filtered on.
+type FieldRegistry struct {
+	fields map[SearchField]Field
+	names  []SearchField
+}
+
+// NewFieldRegistry creates a registry from fields.
+// A field without Expr uses its Name as the SQL expression.
+func NewFieldRegistry(fields ...Field) *FieldRegistry {
+	r := &FieldRegistry{
+		fields: map[SearchField]Field{},
+		names:  []SearchField{},
+	}
+
+	for _, field := range fields {
+		field.Name = strings.ToLower(field.Name)
+		if field.Expr == "" {
+			field.Expr = field.Name
+		}
+
+		if _, ok := r.fields[field.Name]; !ok {
+			r.names = append(r.names, field.Name)
+		}
+		r.fields[field.Name] = field
+	}
+
+	return r
+}
+
+// Get returns the field registered with name.
+func (r *FieldRegistry) Get(name SearchField) (Field, bool) {
+	field, ok := r.fields[strings.ToLower(name)]
+	return field, ok
+}
+
+// SortableFields returns the names of fields clients can sort on.
+func (r *FieldRegistry) SortableFields() []SearchField {
+	names := []SearchField{}
+	for _, name := range r.names {
+		if r.fields[name].Sortable {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ValidateSorting validates the sort keys against the sortable fields.
+func (r *FieldRegistry) ValidateSorting(s *SortList) error {
+	return s.Validate(r.SortableFields())
+}
+
+// ApplySorting adds the sort keys to the builder using the field SQL expressions.
+func (r *FieldRegistry) ApplySorting(b *dbx.SQLBuilder, s SortList) error {
+	for _, sorting := range s {
+		field, ok := r.Get(sorting.Field)
+		if !ok || !field.Sortable {
+			return fmt.Errorf("invalid sort field: %s", sorting.Field)
+		}
+
+		b.AddSortingNulls(field.Expr, sorting.Order, sorting.Nulls)
+	}
+
+	return nil
+}
+
+// ApplyFilter adds "expr <op> value" to the builder after checking that
+// the field exists, allows the operator and the value matches its type.
+func (r *FieldRegistry) ApplyFilter(b *dbx.SQLBuilder, name SearchField, op dbx.Operator, value any) error {
+	field, ok := r.Get(name)
+	if !ok {
+		return fmt.Errorf("invalid filter field: %s", name)
+	}
+
+	if !field.AllowsOperator(op) {
+		return fmt.Errorf("invalid filter operator for field %s: %s", name, op)
+	}
+
+	if err := field.CheckValue(value); err != nil {
+		return err
+	}
+
+	b.AddCompareFilter(field.Expr, op, value)
+	return nil
+}
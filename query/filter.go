@@ -0,0 +1,199 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mudgallabs/tantra/dbx"
+)
+
+// Filter operators supported by the `filter` tag on top of dbx.Operator.
+const (
+	FilterOpBetween    = "between"
+	FilterOpAny        = "any"
+	FilterOpContains   = "contains"
+	FilterOpStartsWith = "starts_with"
+	FilterOpEndsWith   = "ends_with"
+)
+
+// filterTag is the parsed `filter:"column=created_at,op=between"` struct tag.
+type filterTag struct {
+	column string
+	op     string
+	// ci makes LIKE operators case-insensitive (ILIKE).
+	ci bool
+}
+
+func parseFilterTag(tag string) (filterTag, error) {
+	parsed := filterTag{}
+
+	for _, part := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch key {
+		case "column":
+			parsed.column = value
+		case "op":
+			parsed.op = strings.ToLower(value)
+		case "ci":
+			parsed.ci = true
+		case "":
+		default:
+			return parsed, fmt.Errorf("unknown filter tag option: %s", key)
+		}
+	}
+
+	if parsed.column == "" {
+		return parsed, fmt.Errorf("filter tag %q has no column", tag)
+	}
+
+	switch parsed.op {
+	case FilterOpBetween, FilterOpAny, FilterOpContains, FilterOpStartsWith, FilterOpEndsWith:
+	default:
+		op := dbx.Operator(parsed.op)
+		if !op.IsValid() {
+			return parsed, fmt.Errorf("filter tag %q has invalid op: %s", tag, parsed.op)
+		}
+	}
+
+	return parsed, nil
+}
+
+// ApplyFilters walks the fields of filters, a struct or pointer to struct, and adds
+// a condition to the builder for every field with a `filter` tag, e.g.
+//
+//	type UserFilters struct {
+//		Name      *string        `json:"name" filter:"column=u.name,op=contains,ci"`
+//		Status    []string       `json:"status" filter:"column=u.status,op=any"`
+//		CreatedAt *dbx.DateRange `json:"created_at" filter:"column=u.created_at,op=between"`
+//		MinAge    *int           `json:"min_age" filter:"column=u.age,op=gte"`
+//		Deleted   *bool          `json:"deleted" filter:"column=u.deleted_at,op=null"`
+//	}
+//
+// Nil pointers and zero values are skipped, use a pointer to filter on a zero value.
+// The null and notnull ops take a bool, false applies the opposite operator.
+func ApplyFilters(b *dbx.SQLBuilder, filters any) error {
+	rv := reflect.ValueOf(filters)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("filters must be a struct, got %s", rv.Type())
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tag, ok := rt.Field(i).Tag.Lookup("filter")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		// The value of an unexported field can not be read with reflect.
+		if !rt.Field(i).IsExported() {
+			return fmt.Errorf("field %s: filter tag on an unexported field", rt.Field(i).Name)
+		}
+
+		parsed, err := parseFilterTag(tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", rt.Field(i).Name, err)
+		}
+
+		value := rv.Field(i)
+		if value.IsZero() {
+			continue
+		}
+		for value.Kind() == reflect.Pointer {
+			value = value.Elem()
+		}
+
+		if err := applyFilter(b, parsed, value); err != nil {
+			return fmt.Errorf("field %s: %w", rt.Field(i).Name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyFilter(b *dbx.SQLBuilder, tag filterTag, value reflect.Value) error {
+	switch tag.op {
+	case FilterOpBetween:
+		return applyBetweenFilter(b, tag.column, value)
+
+	case FilterOpAny:
+		if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+			return fmt.Errorf("op %s requires a slice, got %s", tag.op, value.Type())
+		}
+
+		values := make([]any, value.Len())
+		for i := range values {
+			values[i] = value.Index(i).Interface()
+		}
+		b.AddArrayFilter(tag.column, values)
+
+	case FilterOpContains, FilterOpStartsWith, FilterOpEndsWith:
+		if value.Kind() != reflect.String {
+			return fmt.Errorf("op %s requires a string, got %s", tag.op, value.Type())
+		}
+
+		switch tag.op {
+		case FilterOpContains:
+			b.AddContainsFilter(tag.column, value.String(), !tag.ci)
+		case FilterOpStartsWith:
+			b.AddStartsWithFilter(tag.column, value.String(), !tag.ci)
+		case FilterOpEndsWith:
+			b.AddEndsWithFilter(tag.column, value.String(), !tag.ci)
+		}
+
+	case string(dbx.OperatorIsNull), string(dbx.OperatorIsNotNull):
+		if value.Kind() != reflect.Bool {
+			return fmt.Errorf("op %s requires a bool, got %s", tag.op, value.Type())
+		}
+
+		// false asks for the opposite, e.g. `Deleted *bool filter:"column=deleted_at,op=null"`.
+		operator := dbx.Operator(tag.op)
+		if !value.Bool() {
+			operator = negateNullOperator(operator)
+		}
+		b.AddCompareFilter(tag.column, operator, nil)
+
+	default:
+		b.AddCompareFilter(tag.column, dbx.Operator(tag.op), value.Interface())
+	}
+
+	return nil
+}
+
+func negateNullOperator(operator dbx.Operator) dbx.Operator {
+	if operator == dbx.OperatorIsNull {
+		return dbx.OperatorIsNotNull
+	}
+	return dbx.OperatorIsNull
+}
+
+// applyBetweenFilter adds a BETWEEN for a dbx.DateRange or a two element slice.
+// A date range with only one bound set is applied as ">=" or "<=".
+func applyBetweenFilter(b *dbx.SQLBuilder, column string, value reflect.Value) error {
+	if dateRange, ok := value.Interface().(dbx.DateRange); ok {
+		switch {
+		case !dateRange.From.IsZero() && !dateRange.To.IsZero():
+			b.AddBetweenFilter(column, dateRange.From, dateRange.To)
+		case !dateRange.From.IsZero():
+			b.AddCompareFilter(column, dbx.OperatorGTE, dateRange.From)
+		case !dateRange.To.IsZero():
+			b.AddCompareFilter(column, dbx.OperatorLTE, dateRange.To)
+		}
+		return nil
+	}
+
+	if (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && value.Len() == 2 {
+		b.AddBetweenFilter(column, value.Index(0).Interface(), value.Index(1).Interface())
+		return nil
+	}
+
+	return fmt.Errorf("op %s requires a %T or two values, got %s", FilterOpBetween, dbx.DateRange{}, value.Type())
+}
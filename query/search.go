@@ -1,5 +1,7 @@
 package query
 
+import "github.com/mudgallabs/tantra/dbx"
+
 // SearchField will be unique field that can be used for sorting and/or filtering.
 type SearchField = string
 
@@ -7,19 +9,44 @@ type SearchField = string
 // It takes a generic to define resource filters type.
 type SearchPayload[T any] struct {
 	Filters    T          `schema:"filters" json:"filters"`
-	Sort       Sorting    `json:"sort"`
+	Sort       SortList   `schema:"-" json:"sort"`
 	Pagination Pagination `json:"pagination"`
+
+	// QuerySort binds "sort.field=...&sort.order=..." query params (httpx.DecodeQuery),
+	// which can not be decoded into the Sort list. Init moves it into Sort.
+	QuerySort Sorting `schema:"sort" json:"-"`
 }
 
 // Init initialises the payload to be used by the service.
 func (p *SearchPayload[T]) Init(allowedFields []string) error {
 	// Apply pagination defaults.
 	p.Pagination.ApplyDefaults()
+	p.applyQuerySort()
 
 	// Validate and make sure that client is sorting on an allowed field.
 	return p.Sort.Validate(allowedFields)
 }
 
+// InitFields is like Init but validates sorting against the sortable fields of the registry.
+func (p *SearchPayload[T]) InitFields(fields *FieldRegistry) error {
+	p.Pagination.ApplyDefaults()
+	p.applyQuerySort()
+
+	return fields.ValidateSorting(&p.Sort)
+}
+
+// applyQuerySort uses the sorting decoded from the query params when no sort list was given.
+func (p *SearchPayload[T]) applyQuerySort() {
+	if len(p.Sort) == 0 && p.QuerySort.Field != "" {
+		p.Sort = SortList{p.QuerySort}
+	}
+}
+
+// ApplyFilters adds the `filter` tagged fields of Filters to the builder.
+func (p *SearchPayload[T]) ApplyFilters(b *dbx.SQLBuilder) error {
+	return ApplyFilters(b, p.Filters)
+}
+
 // SearchResult is the output of the search performed on a <resource>.
 // It takes a generic to define resource item type.
 type SearchResult[T any] struct {
@@ -1,9 +1,12 @@
 package query
 
 import (
+	"encoding/json"
 	"fmt"
 	"slices"
 	"strings"
+
+	"github.com/mudgallabs/tantra/dbx"
 )
 
 type SortOrder = string
@@ -13,14 +16,23 @@ const (
 	SortOrderDESC = "desc"
 )
 
+type NullsOrder = string
+
+const (
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
 type Sorting struct {
 	Field SearchField `query:"field" schema:"field" json:"field"` // e.g., "created_at"
 	Order SortOrder   `query:"order" schema:"order" json:"order"`
+	Nulls NullsOrder  `query:"nulls" schema:"nulls" json:"nulls,omitempty"`
 }
 
 func (s *Sorting) Validate(allowed []SearchField) error {
 	field := strings.ToLower(s.Field)
 	order := strings.ToLower(s.Order)
+	nulls := strings.ToLower(s.Nulls)
 
 	if field == "" {
 		return nil // No sorting applied — that's OK
@@ -38,8 +50,96 @@ func (s *Sorting) Validate(allowed []SearchField) error {
 		return fmt.Errorf("invalid sor order: %s", s.Order)
 	}
 
+	// Validate nulls placement
+	if nulls != "" && nulls != NullsFirst && nulls != NullsLast {
+		return fmt.Errorf("invalid sort nulls: %s", s.Nulls)
+	}
+
 	s.Field = field
 	s.Order = order
+	s.Nulls = nulls
+
+	return nil
+}
+
+// SortList is an ordered list of sort keys, e.g. "pinned desc, created_at desc, id asc".
+// It decodes from either a single sorting object or an array of them.
+type SortList []Sorting
+
+func (s *SortList) UnmarshalJSON(data []byte) error {
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var sorting Sorting
+		if err := json.Unmarshal(data, &sorting); err != nil {
+			return err
+		}
+
+		*s = SortList{sorting}
+		return nil
+	}
+
+	var sortings []Sorting
+	if err := json.Unmarshal(data, &sortings); err != nil {
+		return err
+	}
+
+	*s = sortings
+	return nil
+}
+
+// Validate validates every sort key against the allowed fields.
+// Keys without a field are dropped and a field can only be sorted on once.
+func (s *SortList) Validate(allowed []SearchField) error {
+	sortings := SortList{}
+
+	for _, sorting := range *s {
+		if err := sorting.Validate(allowed); err != nil {
+			return err
+		}
+
+		if sorting.Field == "" {
+			continue
+		}
+
+		if slices.ContainsFunc(sortings, func(other Sorting) bool { return other.Field == sorting.Field }) {
+			return fmt.Errorf("duplicate sort field: %s", sorting.Field)
+		}
 
+		sortings = append(sortings, sorting)
+	}
+
+	*s = sortings
 	return nil
 }
+
+// Apply adds every sort key to the builder in order.
+func (s SortList) Apply(b *dbx.SQLBuilder) {
+	for _, sorting := range s {
+		b.AddSortingNulls(sorting.Field, sorting.Order, sorting.Nulls)
+	}
+}
+
+// SortFieldRank is the sort field for full-text search relevance, see SortList.ApplyRank.
+// Add it to the allowed fields to let clients sort on it.
+const SortFieldRank SearchField = "rank"
+
+// FullTextSearch is the document a <resource> is full-text searched on.
+type FullTextSearch struct {
+	// Document is the trusted SQL expression to search, e.g. "u.name || ' ' || u.bio".
+	Document string
+
+	// Language is the text search configuration, e.g. "english".
+	Language string
+}
+
+// ApplyRank is like Apply but sorts a SortFieldRank key by the ts_rank of term in the document.
+// The rank key is skipped when term is empty.
+func (s SortList) ApplyRank(b *dbx.SQLBuilder, search FullTextSearch, term string) {
+	for _, sorting := range s {
+		if sorting.Field == SortFieldRank {
+			b.AddRankSorting(search.Document, term, search.Language, sorting.Order)
+			continue
+		}
+
+		b.AddSortingNulls(sorting.Field, sorting.Order, sorting.Nulls)
+	}
+}